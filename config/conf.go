@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 // Config 表示配置文件的结构体
@@ -13,6 +14,7 @@ type Config struct {
 	Redis  *RedisConf  `yaml:"redis"`  // Redis redis配置
 	Logger *LoggerConf `yaml:"logger"` // Logger 日志配置
 	Jwt    *JwtConf    `yaml:"jwt"`    // Jwt jwt配置
+	Rbac   *RbacConf   `yaml:"rbac"`   // Rbac 权限配置
 }
 
 // AppConf app配置
@@ -24,21 +26,56 @@ type AppConf struct {
 
 // DbConf 数据库配置
 type DbConf struct {
+	Driver             string `yaml:"driver"`               // Driver 数据库驱动 mysql(默认)/postgres/sqlite
 	Host               string `yaml:"host"`                 // Host 数据库服务host
 	Port               int    `yaml:"port"`                 // Port 数据库服务port
 	User               string `yaml:"user"`                 // User 数据库服务用户名
 	Password           string `yaml:"password"`             // Password 数据库服务密码
-	Name               string `yaml:"name"`                 // Name 数据库名
+	Name               string `yaml:"name"`                 // Name 数据库名, sqlite 为文件路径
+	SSLMode            string `yaml:"ssl_mode"`             // SSLMode postgres sslmode
 	MaxIdleConnections string `yaml:"max_idle_connections"` // MaxIdleConnections 设置空闲连接池中连接的最大数量
 	MaxOpenConnections string `yaml:"max_open_connections"` // MaxOpenConnections 设置数据库的最大打开连接数
+
+	Replicas      []ReplicaConf `yaml:"replicas"`       // Replicas 只读副本, 为空时不启用读写分离
+	ReplicaPolicy string        `yaml:"replica_policy"` // ReplicaPolicy 副本选择策略 round_robin/weighted
+}
+
+// ReplicaConf 只读副本配置, 键名与 DbConf 一致, 可直接复制主库配置修改; 未设置的用户名/密码/库名继承主库
+type ReplicaConf struct {
+	Host     string `yaml:"host"`     // Host 副本host
+	Port     int    `yaml:"port"`     // Port 副本port, 默认同主库
+	User     string `yaml:"user"`     // User 副本用户名
+	Password string `yaml:"password"` // Password 副本密码
+	Name     string `yaml:"name"`     // Name 副本库名
+	Weight   int    `yaml:"weight"`   // Weight 权重, weighted 策略下生效
 }
 
 // RedisConf redis配置
 type RedisConf struct {
-	Addr string `yaml:"addr"` // Addr redis服务host
-	Port int    `yaml:"port"` // Port redis服务port
+	Mode string `yaml:"mode"` // Mode 部署模式 single(默认)/sentinel/cluster
+	Addr string `yaml:"addr"` // Addr redis服务host, single 模式使用
+	Port int    `yaml:"port"` // Port redis服务port, single 模式使用
 	Pwd  string `yaml:"pwd"`  // Pwd redis服务密码
-	Db   int    `yaml:"db"`   // Db redis服务数据库
+	Db   int    `yaml:"db"`   // Db redis服务数据库, cluster 模式不支持
+
+	Namespace string `yaml:"namespace"` // Namespace key 命名空间, 多个应用共用 redis 时设置, 例如 app 名称
+
+	Addrs       []string `yaml:"addrs"`        // Addrs 节点地址 host:port, sentinel 模式为哨兵地址, cluster 模式为集群节点
+	MasterName  string   `yaml:"master_name"`  // MasterName sentinel 模式主节点名称
+	SentinelPwd string   `yaml:"sentinel_pwd"` // SentinelPwd 哨兵密码
+
+	TLS           bool `yaml:"tls"`             // TLS 是否启用TLS
+	TLSSkipVerify bool `yaml:"tls_skip_verify"` // TLSSkipVerify 跳过证书校验, 仅用于测试环境
+
+	PoolSize     int           `yaml:"pool_size"`      // PoolSize 连接池大小
+	MinIdleConns int           `yaml:"min_idle_conns"` // MinIdleConns 最小空闲连接数
+	DialTimeout  time.Duration `yaml:"dial_timeout"`   // DialTimeout 建连超时, 例如 5s
+	ReadTimeout  time.Duration `yaml:"read_timeout"`   // ReadTimeout 读超时
+	WriteTimeout time.Duration `yaml:"write_timeout"`  // WriteTimeout 写超时
+	PoolTimeout  time.Duration `yaml:"pool_timeout"`   // PoolTimeout 获取连接超时
+
+	SlowThreshold time.Duration `yaml:"slow_threshold"` // SlowThreshold 慢命令阈值, 例如 100ms
+	LogArgs       bool          `yaml:"log_args"`       // LogArgs 日志记录完整命令参数
 }
 
 // LoggerConf 日志配置
@@ -51,6 +88,41 @@ type LoggerConf struct {
 type JwtConf struct {
 	Secret  string `yaml:"secret"`  // Secret jwt密钥
 	Timeout int    `yaml:"timeout"` // Timeout token过期时间 单位 秒/s
+
+	RefreshTimeout int    `yaml:"refresh_timeout"` // RefreshTimeout refresh token过期时间 单位 秒/s, 默认7天
+	IdleTimeout    int    `yaml:"idle_timeout"`    // IdleTimeout 会话滑动过期时间 单位 秒/s, 0 不启用
+	Issuer         string `yaml:"issuer"`          // Issuer 签发方, 默认 core_os
+	Audience       string `yaml:"audience"`        // Audience 接收方, 为空时不校验
+
+	Keys []JwtKeyConf `yaml:"keys"` // Keys 非对称签名密钥, 按顺序加载, 最后一个带私钥的密钥用于签名; 为空时使用 Secret 以 HS256 签名
+}
+
+// JwtKeyConf jwt 签名密钥配置
+type JwtKeyConf struct {
+	ID             string `yaml:"id"`               // ID 密钥标识 kid
+	Algorithm      string `yaml:"algorithm"`        // Algorithm 签名算法 RS256/ES256/EdDSA
+	PrivateKeyFile string `yaml:"private_key_file"` // PrivateKeyFile PEM 私钥文件, 为空时只用于校验
+	PublicKeyFile  string `yaml:"public_key_file"`  // PublicKeyFile PEM 公钥文件
+}
+
+// RbacConf 权限配置, 角色和权限也可存储在数据库; 通过 rbac.PolicyFromConf 转换为策略
+type RbacConf struct {
+	Roles    map[string][]string `yaml:"roles"`     // Roles 角色 -> 权限, 支持 * 和 order:* 通配
+	Users    map[string][]string `yaml:"users"`     // Users 用户名 -> 角色, 与 token 中的角色合并
+	Routes   []RbacRouteConf     `yaml:"routes"`    // Routes 路由策略, 按顺序匹配第一条
+	CacheTTL int                 `yaml:"cache_ttl"` // CacheTTL 策略 redis 缓存时间 单位 秒/s, 默认600
+}
+
+// RbacRouteConf 路由策略配置
+type RbacRouteConf struct {
+	Method     string `yaml:"method"`     // Method 请求方法, 为空匹配所有方法
+	Path       string `yaml:"path"`       // Path 路径模式, * 和 :name 匹配一段, 末尾的 ** 匹配剩余所有段
+	Permission string `yaml:"permission"` // Permission 所需权限
+}
+
+// Rule 路由策略的方法、路径和权限, 供 rbac.PolicyFromConf 转换, config 不依赖 rbac
+func (r RbacRouteConf) Rule() (method, path, permission string) {
+	return r.Method, r.Path, r.Permission
 }
 
 // SingletonConfig 是Config的唯一实例
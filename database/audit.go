@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// SystemUser 后台任务等无登录用户场景使用的审计用户名
+	SystemUser = "system"
+
+	// FieldCreatedUser 创建人字段
+	FieldCreatedUser = "CreatedUser"
+	// FieldUpdatedUser 更新人字段
+	FieldUpdatedUser = "UpdatedUser"
+	// FieldDeletedUser 删除人字段
+	FieldDeletedUser = "DeletedUser"
+
+	auditCallbackName  = "go-core:audit"
+	updateMapCopiedKey = "go-core:update_map_copied"
+)
+
+// WithUser 设置审计用户, 优先于 gin.Context 中由 TokenVerify 设置的 username
+func WithUser(ctx context.Context, username string) context.Context {
+	return pkgs.WithUsername(ctx, username)
+}
+
+// WithSystemUser 设置审计用户为 SystemUser, 用于定时任务、消息消费等后台场景
+func WithSystemUser(ctx context.Context) context.Context {
+	return pkgs.WithUsername(ctx, SystemUser)
+}
+
+// RegisterAuditCallbacks 注册审计回调, 创建/更新/软删除时根据 ctx 中的用户填充
+// CreatedUser/UpdatedUser/DeletedUser 字段; database.New 默认已注册
+func RegisterAuditCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register(auditCallbackName, auditCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(auditCallbackName, auditUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register(auditCallbackName, auditDelete)
+}
+
+// auditUser 当前语句的审计用户
+func auditUser(db *gorm.DB) (string, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SkipHooks {
+		return "", false
+	}
+	return pkgs.UsernameFromContext(db.Statement.Context)
+}
+
+// auditCreate 创建时填充创建人和更新人, 已显式赋值的字段保持不变
+func auditCreate(db *gorm.DB) {
+	user, ok := auditUser(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	for _, name := range []string{FieldCreatedUser, FieldUpdatedUser} {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+
+		switch dest := stmt.Dest.(type) {
+		case map[string]interface{}:
+			setMapIfAbsent(dest, field, user)
+		case []map[string]interface{}:
+			for _, m := range dest {
+				setMapIfAbsent(m, field, user)
+			}
+		default:
+			switch stmt.ReflectValue.Kind() {
+			case reflect.Slice, reflect.Array:
+				for i := 0; i < stmt.ReflectValue.Len(); i++ {
+					setFieldIfZero(db, field, stmt.ReflectValue.Index(i), user)
+				}
+			case reflect.Struct:
+				setFieldIfZero(db, field, stmt.ReflectValue, user)
+			}
+		}
+	}
+}
+
+// auditUpdate 更新时填充更新人, 包括 Updates(map) 批量更新; map 中已显式指定的值保持不变
+func auditUpdate(db *gorm.DB) {
+	user, ok := auditUser(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	field := stmt.Schema.LookUpField(FieldUpdatedUser)
+	if field == nil {
+		return
+	}
+
+	if dest, isMap := updateMap(db); isMap {
+		setMapIfAbsent(dest, field, user)
+		return
+	}
+	stmt.SetColumn(field.DBName, user, true)
+}
+
+// auditDelete 软删除时同时写入删除人. SET 子句仍由 gorm.SoftDeleteDeleteClause 生成,
+// 这里只为 SET 子句设置 Builder, 在 gorm 生成的赋值之后追加删除人
+func auditDelete(db *gorm.DB) {
+	user, ok := auditUser(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	field := stmt.Schema.LookUpField(FieldDeletedUser)
+	if stmt.Unscoped || field == nil || !hasSoftDelete(stmt.Schema) {
+		return
+	}
+
+	set := stmt.Clauses["SET"]
+	set.Builder = func(c clause.Clause, builder clause.Builder) {
+		if assignments, ok := c.Expression.(clause.Set); ok {
+			c.Expression = append(assignments[:len(assignments):len(assignments)],
+				clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: user})
+		}
+		c.Builder = nil
+		c.Build(builder)
+	}
+	stmt.Clauses["SET"] = set
+	stmt.SetColumn(field.DBName, user, true)
+}
+
+// hasSoftDelete 模型是否包含 gorm.DeletedAt 软删除字段
+func hasSoftDelete(s *schema.Schema) bool {
+	for _, c := range s.DeleteClauses {
+		if _, ok := c.(gorm.SoftDeleteDeleteClause); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// updateMap Updates(map) 的更新内容. 回调需要增删字段时复制一份替换 stmt.Dest, 避免修改调用方传入的 map,
+// 同一语句只复制一次
+func updateMap(db *gorm.DB) (map[string]interface{}, bool) {
+	dest, ok := db.Statement.Dest.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if _, copied := db.InstanceGet(updateMapCopiedKey); copied {
+		return dest, true
+	}
+
+	m := make(map[string]interface{}, len(dest)+2)
+	for k, v := range dest {
+		m[k] = v
+	}
+	db.Statement.Dest = m
+	db.InstanceSet(updateMapCopiedKey, true)
+	return m, true
+}
+
+// setFieldIfZero 字段为零值时赋值
+func setFieldIfZero(db *gorm.DB, field *schema.Field, rv reflect.Value, value interface{}) {
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if _, zero := field.ValueOf(db.Statement.Context, rv); zero {
+		_ = db.AddError(field.Set(db.Statement.Context, rv, value))
+	}
+}
+
+// setMapIfAbsent map 中未包含该字段时赋值
+func setMapIfAbsent(m map[string]interface{}, field *schema.Field, value interface{}) {
+	if _, ok := m[field.Name]; ok {
+		return
+	}
+	if _, ok := m[field.DBName]; ok {
+		return
+	}
+	m[field.DBName] = value
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type auditOrder struct {
+	gorm.Model
+	Name        string
+	CreatedUser string
+	UpdatedUser string
+	DeletedUser string
+}
+
+func TestAuditCallbacks(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err = db.AutoMigrate(&auditOrder{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	ctx := WithUser(context.Background(), "alice")
+	orders := []auditOrder{{Name: "a"}, {Name: "b", CreatedUser: "importer"}}
+	if err = db.WithContext(ctx).Create(&orders).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if orders[0].CreatedUser != "alice" || orders[0].UpdatedUser != "alice" || orders[1].CreatedUser != "importer" {
+		t.Errorf("创建人填充错误: %+v", orders)
+	}
+
+	// Updates(map) 批量更新
+	ctx = WithUser(context.Background(), "bob")
+	values := map[string]interface{}{"name": "c"}
+	if err = db.WithContext(ctx).Model(&auditOrder{}).Where("1 = 1").Updates(values).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	if len(values) != 1 {
+		t.Errorf("不应修改调用方的 map: %v", values)
+	}
+	var updated []auditOrder
+	db.Find(&updated)
+	for _, o := range updated {
+		if o.UpdatedUser != "bob" {
+			t.Errorf("批量更新未填充更新人: %+v", o)
+		}
+	}
+
+	// 软删除
+	if err = db.WithContext(WithSystemUser(context.Background())).Delete(&orders[0]).Error; err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var deleted auditOrder
+	if err = db.Unscoped().First(&deleted, orders[0].ID).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if !deleted.DeletedAt.Valid || deleted.DeletedUser != SystemUser {
+		t.Errorf("软删除未填充删除人: %+v", deleted)
+	}
+
+	var remain int64
+	db.Model(&auditOrder{}).Count(&remain)
+	if remain != 1 {
+		t.Errorf("软删除影响了其他记录, remain = %d", remain)
+	}
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/migrate"
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+const (
+	defaultMaxIdleConns     = 10
+	defaultMaxOpenConns     = 100
+	defaultConnectTimeout   = 30 * time.Second
+	defaultRetryInterval    = 500 * time.Millisecond
+	defaultMaxRetryInterval = 5 * time.Second
+)
+
+// Config 数据库客户端配置, 连接池、日志、读写分离等配置对所有驱动通用
+type Config struct {
+	DSNParam
+	Name             string           `json:"name"`               // Name 实例名, 非空时注册为命名实例
+	MaxIdleConns     int              `json:"max_idle_conns"`     // MaxIdleConns 空闲连接池最大连接数, 默认10
+	MaxOpenConns     int              `json:"max_open_conns"`     // MaxOpenConns 最大打开连接数, 默认100
+	ConnMaxLifetime  time.Duration    `json:"conn_max_lifetime"`  // ConnMaxLifetime 连接最大复用时间, 0 不限制
+	ConnectTimeout   time.Duration    `json:"connect_timeout"`    // ConnectTimeout 初始连接最长等待时间, 默认30s
+	RetryInterval    time.Duration    `json:"retry_interval"`     // RetryInterval 初始连接重试间隔, 指数退避, 默认500ms
+	MaxRetryInterval time.Duration    `json:"max_retry_interval"` // MaxRetryInterval 重试间隔上限, 默认5s
+	LogLevel         gormLog.LogLevel `json:"log_level"`          // LogLevel gorm日志级别, 默认Info
+	DisableAudit     bool             `json:"disable_audit"`      // DisableAudit 关闭创建人/更新人/删除人自动填充
+	StatsInterval    time.Duration    `json:"stats_interval"`     // StatsInterval 连接池统计日志间隔, 0 不输出
+
+	Replicas            []Replica     `json:"replicas"`              // Replicas 只读副本, 为空时不启用读写分离
+	ReplicaPolicy       string        `json:"replica_policy"`        // ReplicaPolicy 副本选择策略 round_robin(默认)/weighted
+	HealthCheckInterval time.Duration `json:"health_check_interval"` // HealthCheckInterval 副本健康检查间隔, 默认10s
+
+	Migrations       fs.FS            `json:"-"`                 // Migrations 迁移文件目录, 非空时连接成功后执行待执行的迁移
+	MigrationOptions *migrate.Options `json:"migration_options"` // MigrationOptions 迁移配置, 为空时使用默认配置
+
+	sleep func(ctx context.Context, d time.Duration) error // sleep 重试等待, 为空时使用 sleepContext, 测试中替换以避免真实等待
+}
+
+// New 按 Driver 创建DB客户端, 初始连接失败时按退避策略重试直到 ConnectTimeout, 并通过 ping 校验连接
+func New(ctx context.Context, cfg *Config) (*gorm.DB, error) {
+	c := cfg.withDefaults()
+	logger.Logger.Info("DB", zap.String("conn", "connecting..."), zap.String("name", c.Name), zap.String("driver", c.driver()), zap.Stringer("dsn", c))
+
+	// 先连接成功再创建 gorm 实例: mysql 方言初始化时会查询服务器版本, 直接 gorm.Open 会在首次连接失败时立即返回
+	sqlDB, err := sql.Open(c.sqlDriverName(), c.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("database: open %s: %w", c, err)
+	}
+	dialector, err := c.dialector(sqlDB)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+
+	connectCtx, cancel := context.WithTimeout(ctx, c.ConnectTimeout)
+	defer cancel()
+	if err = c.connect(connectCtx, sqlDB); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:               logger.NewCustomLogger(logger.Logger, context.Background(), c.LogLevel),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("database: open %s: %w", c, err)
+	}
+
+	if err = RegisterTenantCallbacks(db); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("database: register tenant callbacks: %w", err)
+	}
+	// 审计回调需先于乐观锁注册, 结构体更新时乐观锁回调生成的 SET 子句才包含审计字段
+	if !c.DisableAudit {
+		if err = RegisterAuditCallbacks(db); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("database: register audit callbacks: %w", err)
+		}
+	}
+	if err = RegisterOptimisticLockCallbacks(db); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("database: register optimistic lock callbacks: %w", err)
+	}
+
+	if len(c.Replicas) > 0 {
+		r, err := newResolver(connectCtx, c)
+		if err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("database: open replicas: %w", err)
+		}
+		if err = db.Use(r); err != nil {
+			_ = r.Close()
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("database: use resolver: %w", err)
+		}
+	}
+
+	if c.StatsInterval > 0 {
+		name := c.Name
+		if name == "" {
+			name = c.driver()
+		}
+		stop := pkgs.StartPoolStatsLogger(c.StatsInterval, NewHealthChecker(name, db))
+		if err = db.Use(&poolStatsPlugin{stop: stop}); err != nil {
+			stop()
+			_ = closeDB(db)
+			return nil, fmt.Errorf("database: use pool stats: %w", err)
+		}
+	}
+
+	if c.Migrations != nil {
+		if err = runMigrations(ctx, db, c); err != nil {
+			_ = closeDB(db)
+			return nil, err
+		}
+	}
+
+	if c.Name != "" {
+		Register(c.Name, db)
+	}
+	logger.Logger.Info("DB", zap.String("conn", "数据库连接成功"), zap.String("name", c.Name))
+	return db, nil
+}
+
+// runMigrations 执行启动迁移, 迁移在主库上执行
+func runMigrations(ctx context.Context, db *gorm.DB, c *Config) error {
+	m := migrate.New(db, c.MigrationOptions)
+	if err := m.LoadFS(c.Migrations); err != nil {
+		return fmt.Errorf("database: load migrations: %w", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		return fmt.Errorf("database: migrate: %w", err)
+	}
+	return nil
+}
+
+// connect ping 校验连接, 失败时按指数退避重试直到 ctx 结束, 返回的错误包装最后一次连接错误
+func (c *Config) connect(ctx context.Context, sqlDB *sql.DB) error {
+	sleep := c.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	interval := c.RetryInterval
+	for attempt := 1; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		logger.Logger.Warn("DB", zap.String("conn", "retrying..."), zap.String("name", c.Name), zap.Int("attempt", attempt), zap.Error(err))
+
+		if sleepErr := sleep(ctx, interval); sleepErr != nil {
+			return fmt.Errorf("database: connect %s after %d attempts: %w", c, attempt, err)
+		}
+
+		interval *= 2
+		if interval > c.MaxRetryInterval {
+			interval = c.MaxRetryInterval
+		}
+	}
+}
+
+// sleepContext 等待 d, ctx 先结束时返回 ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// dialector 按驱动选择 gorm 方言, 使用已建立的连接池 conn
+func (c *Config) dialector(conn *sql.DB) (gorm.Dialector, error) {
+	switch c.driver() {
+	case DriverMySQL:
+		return mysql.New(mysql.Config{DSN: c.DSN(), Conn: conn}), nil
+	case DriverPostgres:
+		return postgres.New(postgres.Config{DSN: c.DSN(), Conn: conn}), nil
+	case DriverSQLite:
+		return &sqlite.Dialector{DSN: c.DSN(), Conn: conn}, nil
+	default:
+		return nil, fmt.Errorf("database: unsupported driver %q", c.Driver)
+	}
+}
+
+// sqlDriverName 驱动对应的 database/sql 注册名
+func (h *DSNParam) sqlDriverName() string {
+	switch h.driver() {
+	case DriverPostgres:
+		return "pgx"
+	case DriverSQLite:
+		return sqlite.DriverName
+	default:
+		return DriverMySQL
+	}
+}
+
+// withDefaults 返回填充默认值后的配置副本
+func (c *Config) withDefaults() *Config {
+	cp := *c
+	cp.Driver = cp.driver()
+	if cp.MaxIdleConns <= 0 {
+		cp.MaxIdleConns = defaultMaxIdleConns
+	}
+	if cp.MaxOpenConns <= 0 {
+		cp.MaxOpenConns = defaultMaxOpenConns
+	}
+	if cp.ConnectTimeout <= 0 {
+		cp.ConnectTimeout = defaultConnectTimeout
+	}
+	if cp.RetryInterval <= 0 {
+		cp.RetryInterval = defaultRetryInterval
+	}
+	if cp.MaxRetryInterval <= 0 {
+		cp.MaxRetryInterval = defaultMaxRetryInterval
+	}
+	if cp.MaxRetryInterval < cp.RetryInterval {
+		cp.MaxRetryInterval = cp.RetryInterval
+	}
+	if cp.LogLevel == 0 {
+		cp.LogLevel = gormLog.Info
+	}
+	return &cp
+}
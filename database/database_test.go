@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// newTestDB 创建内存 sqlite 实例, 供包内测试使用
+func newTestDB(t *testing.T) *Config {
+	t.Helper()
+	return &Config{
+		DSNParam:     DSNParam{Driver: DriverSQLite, DbName: testutil.SQLiteDSN(t)},
+		MaxOpenConns: 1,
+	}
+}
+
+func TestNewSQLite(t *testing.T) {
+	cfg := newTestDB(t)
+	cfg.Name = "sqlite_test"
+
+	db, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer Close(cfg.Name)
+
+	if got, ok := Get(cfg.Name); !ok || got != db {
+		t.Fatalf("命名实例未注册")
+	}
+
+	var n int
+	if err := db.Raw("SELECT 1").Scan(&n).Error; err != nil || n != 1 {
+		t.Fatalf("查询失败: %v, %d", err, n)
+	}
+}
+
+func TestNewUnsupportedDriver(t *testing.T) {
+	_, err := New(context.Background(), &Config{DSNParam: DSNParam{Driver: "oracle"}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported driver") {
+		t.Fatalf("应返回不支持的驱动错误, got %v", err)
+	}
+}
+
+func TestNewRetryUntilDeadline(t *testing.T) {
+	// 记录每次退避间隔, 第6次等待时模拟 ConnectTimeout 到期, 不真实等待
+	var intervals []time.Duration
+	cfg := &Config{
+		// 端口1无服务监听, 每次连接立即被拒绝
+		DSNParam:         DSNParam{DbHost: "127.0.0.1", DbPort: 1, DbUser: "core", DbPwd: "s3cret", DbName: "core"},
+		ConnectTimeout:   time.Minute,
+		RetryInterval:    10 * time.Millisecond,
+		MaxRetryInterval: 80 * time.Millisecond,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			intervals = append(intervals, d)
+			if len(intervals) == 6 {
+				return context.DeadlineExceeded
+			}
+			return nil
+		},
+	}
+
+	_, err := New(context.Background(), cfg)
+	if err == nil {
+		t.Fatalf("不可达的数据库应返回错误")
+	}
+
+	ms := time.Millisecond
+	want := []time.Duration{10 * ms, 20 * ms, 40 * ms, 80 * ms, 80 * ms, 80 * ms}
+	if fmt.Sprint(intervals) != fmt.Sprint(want) {
+		t.Fatalf("退避间隔 = %v, want %v", intervals, want)
+	}
+
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("应包装最后一次连接错误, got %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") || !strings.HasPrefix(err.Error(), "database: connect core:***@tcp(127.0.0.1:1)/core?charset=utf8mb4&parseTime=True&loc=Local after 6 attempts: ") {
+		t.Fatalf("错误应包含脱敏后的连接信息和重试次数: %v", err)
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	param := &DSNParam{Driver: DriverPostgres, DbHost: "127.0.0.1", DbPort: 5432, DbUser: "core", DbPwd: "pa ss", DbName: "core"}
+
+	if dsn := param.DSN(); dsn != "host=127.0.0.1 port=5432 user=core password='pa ss' dbname=core sslmode=disable" {
+		t.Errorf("DSN = %s", dsn)
+	}
+	if dsn := param.RedactedDSN(); strings.Contains(dsn, "pa ss") || !strings.Contains(dsn, "password=***") {
+		t.Errorf("RedactedDSN 泄露了密码: %s", dsn)
+	}
+}
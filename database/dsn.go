@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bigbigliu/go-core/pkgs"
+)
+
+const (
+	// DriverMySQL mysql驱动
+	DriverMySQL = "mysql"
+	// DriverPostgres postgres驱动
+	DriverPostgres = "postgres"
+	// DriverSQLite sqlite驱动, DbName 为数据库文件路径, ":memory:" 为内存库
+	DriverSQLite = "sqlite"
+)
+
+// DSNParam 生成dsn参数
+type DSNParam struct {
+	Driver  string `json:"driver"`   // Driver 数据库驱动 mysql(默认)/postgres/sqlite
+	DbHost  string `json:"db_host"`  // DbHost 数据库服务host
+	DbPort  int    `json:"db_port"`  // DbPort 数据库服务port
+	DbUser  string `json:"db_user"`  // DbUser 数据库服务用户名
+	DbPwd   string `json:"db_pwd"`   // DbPwd 数据库服务密码
+	DbName  string `json:"db_name"`  // DbName 数据库名, sqlite 为文件路径
+	SSLMode string `json:"ssl_mode"` // SSLMode postgres sslmode, 默认disable
+	Params  string `json:"params"`   // Params 附加dsn参数, 为空时使用各驱动的默认参数
+}
+
+// DSN dsn, 包含明文密码, 禁止直接输出到日志
+func (h *DSNParam) DSN() string {
+	return h.formatDSN(h.DbPwd)
+}
+
+// RedactedDSN 脱敏后的dsn, 用于日志输出
+func (h *DSNParam) RedactedDSN() string {
+	return h.formatDSN(pkgs.RedactSecret(h.DbPwd))
+}
+
+// String 实现 fmt.Stringer, 只输出脱敏后的连接信息
+func (h DSNParam) String() string {
+	return h.RedactedDSN()
+}
+
+// driver 驱动名, 未设置时为mysql
+func (h *DSNParam) driver() string {
+	if h.Driver == "" {
+		return DriverMySQL
+	}
+	return h.Driver
+}
+
+// formatDSN 按驱动和指定密码拼接dsn
+func (h *DSNParam) formatDSN(pwd string) string {
+	switch h.driver() {
+	case DriverPostgres:
+		sslMode := h.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			quotePgValue(h.DbHost), h.DbPort, quotePgValue(h.DbUser), quotePgValue(pwd), quotePgValue(h.DbName), sslMode)
+		if h.Params != "" {
+			dsn += " " + h.Params
+		}
+		return dsn
+	case DriverSQLite:
+		if h.Params != "" {
+			return h.DbName + "?" + h.Params
+		}
+		return h.DbName
+	default:
+		params := h.Params
+		if params == "" {
+			params = "charset=utf8mb4&parseTime=True&loc=Local"
+		}
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", h.DbUser, pwd, h.DbHost, h.DbPort, h.DbName, params)
+	}
+}
+
+// quotePgValue postgres key=value 格式中对含空格或引号的值加引号
+func quotePgValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+// ErrNotInitialized 数据库连接尚未初始化
+var ErrNotInitialized = errors.New("database: not initialized")
+
+// healthChecker 数据库健康检查
+type healthChecker struct {
+	name string
+	db   func() *gorm.DB // db 每次检查时获取连接, 兼容先注册检查、后初始化连接的全局实例
+}
+
+// NewHealthChecker 创建数据库健康检查, 检查主库连通性并统计主库连接池
+func NewHealthChecker(name string, db *gorm.DB) pkgs.HealthChecker {
+	return NewHealthCheckerFunc(name, func() *gorm.DB { return db })
+}
+
+// NewHealthCheckerFunc 创建数据库健康检查, 连接在每次检查时通过 db 获取, 用于 mysql.DBClient 等全局实例
+func NewHealthCheckerFunc(name string, db func() *gorm.DB) pkgs.HealthChecker {
+	return &healthChecker{name: name, db: db}
+}
+
+// Name 依赖名称
+func (h *healthChecker) Name() string {
+	return h.name
+}
+
+// Check ping 主库, 连接未初始化时返回 ErrNotInitialized
+func (h *healthChecker) Check(ctx context.Context) error {
+	db := h.db()
+	if db == nil {
+		return ErrNotInitialized
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Stats 主库连接池统计, 连接未初始化时为空
+func (h *healthChecker) Stats() pkgs.PoolStats {
+	db := h.db()
+	if db == nil {
+		return pkgs.PoolStats{}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return pkgs.PoolStats{}
+	}
+	return poolStats(sqlDB.Stats())
+}
+
+// poolStats sql.DBStats 转换为通用统计
+func poolStats(s sql.DBStats) pkgs.PoolStats {
+	return pkgs.PoolStats{
+		MaxOpen:      s.MaxOpenConnections,
+		Open:         s.OpenConnections,
+		InUse:        s.InUse,
+		Idle:         s.Idle,
+		WaitCount:    s.WaitCount,
+		WaitDuration: s.WaitDuration,
+	}
+}
+
+// poolStatsPlugin 定时输出连接池统计, database.Close 时停止
+type poolStatsPlugin struct {
+	stop func()
+}
+
+// Name 实现 gorm.Plugin
+func (p *poolStatsPlugin) Name() string {
+	return "go-core:pool_stats"
+}
+
+// Initialize 实现 gorm.Plugin
+func (p *poolStatsPlugin) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// Close 停止输出
+func (p *poolStatsPlugin) Close() error {
+	p.stop()
+	return nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+func TestHealthChecker(t *testing.T) {
+	cfg := newTestDB(t)
+	cfg.Name = "health_test"
+	cfg.StatsInterval = time.Hour
+
+	db, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	list := pkgs.CheckHealth(context.Background(), time.Second, NewHealthChecker("sqlite", db))
+	if len(list) != 1 || !list[0].Healthy || list[0].Stats.MaxOpen != 1 {
+		t.Fatalf("健康检查结果不正确: %+v", list)
+	}
+
+	if err = Close(cfg.Name); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	list = pkgs.CheckHealth(context.Background(), time.Second, NewHealthChecker("sqlite", db))
+	if list[0].Healthy || list[0].Error == "" {
+		t.Fatalf("关闭后应检查失败: %+v", list)
+	}
+}
+
+func TestHealthCheckerFuncNotInitialized(t *testing.T) {
+	var db *gorm.DB
+	checker := NewHealthCheckerFunc("sqlite", func() *gorm.DB { return db })
+	if err := checker.Check(context.Background()); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("未初始化时应返回 ErrNotInitialized, got %v", err)
+	}
+	if stats := checker.Stats(); stats != (pkgs.PoolStats{}) {
+		t.Fatalf("未初始化时统计应为空: %+v", stats)
+	}
+
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = closeDB(db) })
+	if err = checker.Check(context.Background()); err != nil {
+		t.Fatalf("初始化后检查失败: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"io"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultInstance 默认实例名, mysql.InitDB 使用该名称注册
+	DefaultInstance = "default"
+)
+
+var (
+	instances   = make(map[string]*gorm.DB)
+	instancesMu sync.RWMutex
+)
+
+// Register 注册命名DB实例, 同名实例会被覆盖
+func Register(name string, db *gorm.DB) {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+	instances[name] = db
+}
+
+// Get 获取命名DB实例
+func Get(name string) (*gorm.DB, bool) {
+	instancesMu.RLock()
+	defer instancesMu.RUnlock()
+	db, ok := instances[name]
+	return db, ok
+}
+
+// Close 关闭并移除命名DB实例
+func Close(name string) error {
+	instancesMu.Lock()
+	db, ok := instances[name]
+	delete(instances, name)
+	instancesMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return closeDB(db)
+}
+
+// closeDB 关闭实现了 io.Closer 的插件(如只读副本)和连接池
+func closeDB(db *gorm.DB) error {
+	for _, plugin := range db.Config.Plugins {
+		if closer, ok := plugin.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,88 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// Run 执行迁移子命令, 便于服务在自己的命令行中挂载:
+//
+//	up [--dry-run]          执行所有待执行的迁移
+//	down [n] [--dry-run]    回滚最近 n 个迁移, 默认1
+//	status                  打印迁移状态
+func (m *Migrator) Run(ctx context.Context, args []string, out io.Writer) error {
+	var (
+		cmd    string
+		steps  = 1
+		dryRun = m.opts.DryRun
+	)
+	for _, arg := range args {
+		switch {
+		case arg == "--dry-run":
+			dryRun = true
+		case cmd == "":
+			cmd = arg
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("migrate: invalid steps %q", arg)
+			}
+			steps = n
+		}
+	}
+
+	switch cmd {
+	case "up":
+		list, err := m.up(ctx, dryRun)
+		m.printPlan(out, "up", list, dryRun)
+		return err
+	case "down":
+		list, err := m.down(ctx, steps, dryRun)
+		m.printPlan(out, "down", list, dryRun)
+		return err
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range list {
+			state, appliedAt := "pending", ""
+			switch {
+			case s.Missing:
+				state = "missing"
+			case s.Modified:
+				state = "modified"
+			case s.Applied:
+				state = "applied"
+			}
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("migrate: unknown command %q, expected up/down/status", cmd)
+	}
+}
+
+// printPlan 打印本次执行或 dry-run 计划执行的迁移
+func (m *Migrator) printPlan(out io.Writer, direction string, list []*Migration, dryRun bool) {
+	prefix := ""
+	if dryRun {
+		prefix = "[dry-run] "
+	}
+	if len(list) == 0 {
+		fmt.Fprintf(out, "%sno migrations to %s\n", prefix, direction)
+		return
+	}
+	for _, mg := range list {
+		fmt.Fprintf(out, "%s%s %d_%s\n", prefix, direction, mg.Version, mg.Name)
+	}
+}
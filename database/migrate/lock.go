@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/bigbigliu/go-core/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// lock 在 conn 所在会话上获取数据库咨询锁, 返回释放函数; mysql 使用 GET_LOCK, postgres 使用 pg_advisory_lock,
+// sqlite 为单机文件库, 不加锁. conn 需为 db.Connection 固定的独占连接, 连接关闭前需调用释放函数
+func (m *Migrator) lock(ctx context.Context, conn *gorm.DB) (func(), error) {
+	dialect := conn.Dialector.Name()
+	if dialect != "mysql" && dialect != "postgres" {
+		return func() {}, nil
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, m.opts.LockTimeout)
+	defer cancel()
+
+	var (
+		err     error
+		release string
+		args    []interface{}
+	)
+	switch dialect {
+	case "mysql":
+		var got sql.NullInt64
+		seconds := int(math.Ceil(m.opts.LockTimeout.Seconds()))
+		err = conn.WithContext(lockCtx).Raw("SELECT GET_LOCK(?, ?)", m.opts.LockName, seconds).Row().Scan(&got)
+		if err == nil && got.Int64 != 1 {
+			err = ErrLockTimeout
+		}
+		release, args = "SELECT RELEASE_LOCK(?)", []interface{}{m.opts.LockName}
+	case "postgres":
+		key := advisoryKey(m.opts.LockName)
+		err = conn.WithContext(lockCtx).Exec("SELECT pg_advisory_lock(?)", key).Error
+		if err != nil && errors.Is(lockCtx.Err(), context.DeadlineExceeded) {
+			err = ErrLockTimeout
+		}
+		release, args = "SELECT pg_advisory_unlock(?)", []interface{}{key}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("migrate: acquire lock %q: %w", m.opts.LockName, err)
+	}
+
+	return func() {
+		if err := conn.WithContext(context.WithoutCancel(ctx)).Exec(release, args...).Error; err != nil {
+			logger.Logger.Warn("Migrate", zap.String("lock", m.opts.LockName), zap.String("msg", "释放迁移锁失败"), zap.Error(err))
+		}
+	}, nil
+}
+
+// advisoryKey postgres 咨询锁的 bigint 键
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,380 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTable       = "schema_migrations"
+	defaultLockName    = "go-core:migrate"
+	defaultLockTimeout = 60 * time.Second
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移文件被修改
+	ErrChecksumMismatch = errors.New("migrate: checksum mismatch")
+	// ErrMissingDown 迁移没有回滚脚本
+	ErrMissingDown = errors.New("migrate: down migration not found")
+	// ErrLockTimeout 获取迁移锁超时, 通常是其他实例正在迁移
+	ErrLockTimeout = errors.New("migrate: lock timeout")
+
+	fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration 单个版本的迁移, SQL 迁移和 Go 迁移二选一
+type Migration struct {
+	Version  int64                                        // Version 版本号, 按升序执行
+	Name     string                                       // Name 迁移名称
+	UpSQL    string                                       // UpSQL 升级SQL, 可包含多条以分号结尾的语句
+	DownSQL  string                                       // DownSQL 回滚SQL
+	Up       func(ctx context.Context, tx *gorm.DB) error // Up Go 升级函数
+	Down     func(ctx context.Context, tx *gorm.DB) error // Down Go 回滚函数
+	checksum string
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // Modified 已执行后文件被修改
+	Missing   bool       `json:"missing"`  // Missing 已执行但源中不存在
+}
+
+// Options 迁移配置
+type Options struct {
+	Table       string        // Table 迁移记录表, 默认 schema_migrations
+	LockName    string        // LockName 迁移锁名称, 多个服务共享一个库时需区分, 默认 go-core:migrate
+	LockTimeout time.Duration // LockTimeout 等待迁移锁的最长时间, 默认60s
+	DryRun      bool          // DryRun 只打印待执行的迁移, 不修改数据库: 不加锁也不创建迁移记录表, 记录表不存在时视为没有已执行的迁移
+}
+
+// record 迁移记录
+type record struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255"`
+	Checksum  string    `gorm:"size:64"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migrator 版本化迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	opts       Options
+	migrations map[int64]*Migration
+}
+
+// New 创建迁移执行器, opts 为 nil 时使用默认配置
+func New(db *gorm.DB, opts *Options) *Migrator {
+	m := &Migrator{db: db, migrations: make(map[int64]*Migration)}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Table == "" {
+		m.opts.Table = defaultTable
+	}
+	if m.opts.LockName == "" {
+		m.opts.LockName = defaultLockName
+	}
+	if m.opts.LockTimeout <= 0 {
+		m.opts.LockTimeout = defaultLockTimeout
+	}
+	return m
+}
+
+// Register 注册 Go 迁移或手工构造的 SQL 迁移, 版本号重复时返回错误
+func (m *Migrator) Register(migrations ...*Migration) error {
+	for _, mg := range migrations {
+		if _, ok := m.migrations[mg.Version]; ok {
+			return fmt.Errorf("migrate: duplicate version %d", mg.Version)
+		}
+		if mg.UpSQL != "" {
+			mg.checksum = checksum(mg.UpSQL)
+		}
+		m.migrations[mg.Version] = mg
+	}
+	return nil
+}
+
+// LoadFS 从 fsys 根目录加载 SQL 迁移, 文件名格式 0001_create_users.up.sql / 0001_create_users.down.sql,
+// 通常配合 embed.FS 与 fs.Sub 使用
+func (m *Migrator) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return err
+		}
+
+		mg, ok := loaded[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			loaded[version] = mg
+		} else if mg.Name != match[2] {
+			return fmt.Errorf("migrate: version %d has different names %q and %q", version, mg.Name, match[2])
+		}
+		if match[3] == "up" {
+			mg.UpSQL = string(content)
+		} else {
+			mg.DownSQL = string(content)
+		}
+	}
+
+	for _, mg := range loaded {
+		if mg.UpSQL == "" {
+			return fmt.Errorf("migrate: version %d has no up migration", mg.Version)
+		}
+		if err = m.Register(mg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Up 执行所有待执行的迁移, 返回本次执行(DryRun 时为待执行)的迁移
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	return m.up(ctx, m.opts.DryRun)
+}
+
+// up 执行或 dryRun 时只列出待执行的迁移
+func (m *Migrator) up(ctx context.Context, dryRun bool) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, dryRun, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if err = m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, mg := range m.sorted() {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if dryRun {
+				logger.Logger.Info("Migrate", zap.String("dry_run", "up"), zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.String("sql", mg.UpSQL))
+				done = append(done, mg)
+				continue
+			}
+
+			if err = m.run(ctx, db, mg, true); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本倒序回滚最近 steps 个已执行的迁移, 返回本次回滚(DryRun 时为待回滚)的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	return m.down(ctx, steps, m.opts.DryRun)
+}
+
+// down 回滚或 dryRun 时只列出待回滚的迁移
+func (m *Migrator) down(ctx context.Context, steps int, dryRun bool) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, dryRun, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for i := 0; i < steps && i < len(versions); i++ {
+			mg, ok := m.migrations[versions[i]]
+			if !ok || (mg.DownSQL == "" && mg.Down == nil) {
+				return fmt.Errorf("%w: version %d", ErrMissingDown, versions[i])
+			}
+			if dryRun {
+				logger.Logger.Info("Migrate", zap.String("dry_run", "down"), zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.String("sql", mg.DownSQL))
+				done = append(done, mg)
+				continue
+			}
+
+			if err = m.run(ctx, db, mg, false); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 所有迁移的执行状态, 按版本升序; 只读, 迁移记录表不存在时视为没有已执行的迁移
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Status
+	for _, mg := range m.sorted() {
+		s := Status{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			appliedAt := r.AppliedAt
+			s.Applied, s.AppliedAt = true, &appliedAt
+			s.Modified = r.Checksum != "" && mg.checksum != "" && r.Checksum != mg.checksum
+		}
+		list = append(list, s)
+	}
+	for v, r := range applied {
+		if _, ok := m.migrations[v]; !ok {
+			appliedAt := r.AppliedAt
+			list = append(list, Status{Version: v, Name: r.Name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// run 在事务中执行单个迁移并更新迁移记录
+func (m *Migrator) run(ctx context.Context, db *gorm.DB, mg *Migration, up bool) error {
+	begin := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		script, fn := mg.DownSQL, mg.Down
+		if up {
+			script, fn = mg.UpSQL, mg.Up
+		}
+
+		if fn != nil {
+			if err := fn(ctx, tx); err != nil {
+				return err
+			}
+		}
+		for _, stmt := range splitStatements(script, tx.Dialector.Name() == "mysql") {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+
+		if !up {
+			return tx.Table(m.opts.Table).Where("version = ?", mg.Version).Delete(&record{}).Error
+		}
+		return tx.Table(m.opts.Table).Create(&record{
+			Version:   mg.Version,
+			Name:      mg.Name,
+			Checksum:  mg.checksum,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	if err != nil {
+		logger.Logger.Error("Migrate", zap.String("direction", direction), zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.Error(err))
+		return fmt.Errorf("migrate: %s %d_%s: %w", direction, mg.Version, mg.Name, err)
+	}
+	logger.Logger.Info("Migrate", zap.String("direction", direction), zap.Int64("version", mg.Version), zap.String("name", mg.Name), zap.Duration("elapsed", time.Since(begin)))
+	return nil
+}
+
+// withLock 持有迁移锁执行 fn, 防止多个实例并发迁移. 咨询锁与会话绑定, 加锁、建表和执行迁移都在同一个独占连接上,
+// 因此 MaxOpenConns=1 时不会因锁占用唯一连接而阻塞. dryRun 时不加锁也不建表
+func (m *Migrator) withLock(ctx context.Context, dryRun bool, fn func(db *gorm.DB) error) error {
+	if dryRun {
+		return fn(m.db.WithContext(ctx))
+	}
+
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err = m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// ensureTable 创建迁移记录表
+func (m *Migrator) ensureTable(db *gorm.DB) error {
+	return db.Table(m.opts.Table).AutoMigrate(&record{})
+}
+
+// applied 已执行的迁移记录, 记录表不存在时为空
+func (m *Migrator) applied(db *gorm.DB) (map[int64]record, error) {
+	if !db.Migrator().HasTable(m.opts.Table) {
+		return map[int64]record{}, nil
+	}
+
+	var records []record
+	if err := db.Table(m.opts.Table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// verify 校验已执行的迁移未被修改
+func (m *Migrator) verify(applied map[int64]record) error {
+	for v, r := range applied {
+		mg, ok := m.migrations[v]
+		if !ok {
+			logger.Logger.Warn("Migrate", zap.Int64("version", v), zap.String("name", r.Name), zap.String("msg", "已执行的迁移在源中不存在"))
+			continue
+		}
+		if r.Checksum != "" && mg.checksum != "" && r.Checksum != mg.checksum {
+			return fmt.Errorf("%w: version %d_%s", ErrChecksumMismatch, v, mg.Name)
+		}
+	}
+	return nil
+}
+
+// sorted 按版本升序的迁移
+func (m *Migrator) sorted() []*Migration {
+	list := make([]*Migration, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		list = append(list, mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// checksum sha256
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,220 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// newTestDB 内存 sqlite
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testutil.NewSQLite(t)
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);\n-- 初始数据; 注释中的分号\nINSERT INTO users (name) VALUES ('a;b');\n")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	m := New(db, nil)
+	if err := m.LoadFS(testFS()); err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+	if err := m.Register(&Migration{
+		Version: 3,
+		Name:    "seed_admin",
+		Up: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("INSERT INTO users (name, email) VALUES (?, ?)", "admin", "admin@example.com").Error
+		},
+		Down: func(ctx context.Context, tx *gorm.DB) error {
+			return tx.Exec("DELETE FROM users WHERE name = ?", "admin").Error
+		},
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	done, err := m.Up(ctx)
+	if err != nil || len(done) != 3 {
+		t.Fatalf("Up: %v, %d", err, len(done))
+	}
+	var name string
+	if err = db.Raw("SELECT name FROM users WHERE id = 1").Scan(&name).Error; err != nil || name != "a;b" {
+		t.Fatalf("引号内分号不应拆分: %v, %q", err, name)
+	}
+
+	// 重复执行为空操作
+	if done, err = m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("重复 Up: %v, %d", err, len(done))
+	}
+
+	if done, err = m.Down(ctx, 2); err != nil || len(done) != 2 || done[0].Version != 3 || done[1].Version != 2 {
+		t.Fatalf("Down: %v, %v", err, done)
+	}
+
+	list, err := m.Status(ctx)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("Status: %v, %d", err, len(list))
+	}
+	if !list[0].Applied || list[1].Applied || list[2].Applied {
+		t.Fatalf("状态不正确: %+v", list)
+	}
+
+	var out bytes.Buffer
+	if err = m.Run(ctx, []string{"status"}, &out); err != nil {
+		t.Fatalf("Run status: %v", err)
+	}
+	if !strings.Contains(out.String(), "add_email") || !strings.Contains(out.String(), "pending") {
+		t.Fatalf("status 输出不正确:\n%s", out.String())
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	m := New(db, nil)
+	if err := m.LoadFS(testFS()); err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := m.Run(ctx, []string{"up", "--dry-run"}, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(out.String(), "[dry-run] up 1_create_users") {
+		t.Fatalf("dry-run 输出不正确:\n%s", out.String())
+	}
+	if db.Migrator().HasTable("users") || db.Migrator().HasTable(m.opts.Table) {
+		t.Fatalf("dry-run 不应修改数据库")
+	}
+
+	// --dry-run 只作用于当次命令, 之后不带参数的命令正常执行
+	out.Reset()
+	if err := m.Run(ctx, []string{"up"}, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.Contains(out.String(), "[dry-run]") || !db.Migrator().HasTable("users") {
+		t.Fatalf("dry-run 之后的 up 应执行迁移:\n%s", out.String())
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		name             string
+		script           string
+		backslashEscapes bool
+		want             []string
+	}{
+		{
+			name:   "引号和行注释",
+			script: "INSERT INTO t VALUES ('a;b', \"c;d\");\n-- x; y\nSELECT 1;",
+			want:   []string{"INSERT INTO t VALUES ('a;b', \"c;d\")", "-- x; y\nSELECT 1"},
+		},
+		{
+			name:   "块注释",
+			script: "/* a; b */ SELECT 1; /* 只有注释; */",
+			want:   []string{"/* a; b */ SELECT 1"},
+		},
+		{
+			name: "postgres 函数体",
+			script: "CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;\n" +
+				"CREATE FUNCTION g() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql",
+				"CREATE FUNCTION g() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql",
+			},
+		},
+		{
+			name:   "postgres DO 块和参数占位符",
+			script: "DO $$ BEGIN PERFORM 1; END $$; SELECT $1;",
+			want:   []string{"DO $$ BEGIN PERFORM 1; END $$", "SELECT $1"},
+		},
+		{
+			name:             "mysql 反斜杠转义",
+			script:           `INSERT INTO t VALUES ('it\'s; fine'); SELECT 1;`,
+			backslashEscapes: true,
+			want:             []string{`INSERT INTO t VALUES ('it\'s; fine')`, "SELECT 1"},
+		},
+		{
+			name:   "postgres 标准字符串中的反斜杠",
+			script: `INSERT INTO t VALUES ('C:\'); SELECT 1;`,
+			want:   []string{`INSERT INTO t VALUES ('C:\')`, "SELECT 1"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := splitStatements(c.script, c.backslashEscapes)
+			if strings.Join(got, "|") != strings.Join(c.want, "|") {
+				t.Fatalf("splitStatements = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	m := New(db, nil)
+	if err := m.LoadFS(testFS()); err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	changed := testFS()
+	changed["0002_add_email.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN mail TEXT;")}
+	m = New(db, nil)
+	if err := m.LoadFS(changed); err != nil {
+		t.Fatalf("LoadFS: %v", err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("应返回 ErrChecksumMismatch, got %v", err)
+	}
+
+	list, err := m.Status(ctx)
+	if err != nil || !list[1].Modified {
+		t.Fatalf("Status 应标记已修改: %v, %+v", err, list)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	m := New(db, nil)
+	_ = m.Register(&Migration{Version: 1, Name: "broken", UpSQL: "CREATE TABLE t1 (id INTEGER);\nINSERT INTO missing VALUES (1);"})
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatalf("应返回错误")
+	}
+
+	list, _ := m.Status(ctx)
+	if len(list) != 1 || list[0].Applied {
+		t.Fatalf("失败的迁移不应记录: %+v", list)
+	}
+	if db.Migrator().HasTable("t1") {
+		t.Fatalf("失败的迁移应回滚")
+	}
+}
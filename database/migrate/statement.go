@@ -0,0 +1,97 @@
+package migrate
+
+import "strings"
+
+// splitStatements 按分号拆分 SQL 脚本, 忽略引号、注释和 postgres $$ 函数体内的分号;
+// mysql 驱动默认不允许一次执行多条语句, 因此逐条执行. backslashEscapes 为 true 时(mysql)
+// 引号内的 \ 转义下一个字符; postgres 标准字符串中的 \ 是普通字符
+func splitStatements(script string, backslashEscapes bool) []string {
+	var (
+		stmts []string
+		start int
+	)
+	flush := func(end int) {
+		if s := strings.TrimSpace(script[start:end]); s != "" && !onlyComments(s) {
+			stmts = append(stmts, s)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, backslashEscapes && c != '`')
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			i = skipUntil(script, i, "\n") - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipUntil(script, i+2, "*/") - 1
+		case c == '$':
+			if tag, ok := dollarTag(script[i:]); ok {
+				i = skipUntil(script, i+len(tag), tag) - 1
+			}
+		case c == ';':
+			flush(i)
+			start = i + 1
+		}
+	}
+	flush(len(script))
+	return stmts
+}
+
+// skipQuoted 跳过从 i 开始的引号字符串, 返回结束引号的位置; 连续两个引号表示引号本身
+func skipQuoted(s string, i int, backslashEscapes bool) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// skipUntil 从 i 开始查找 end, 返回 end 之后的位置, 未找到时返回字符串末尾
+func skipUntil(s string, i int, end string) int {
+	if n := strings.Index(s[i:], end); n >= 0 {
+		return i + n + len(end)
+	}
+	return len(s)
+}
+
+// dollarTag postgres 美元引号的开始标记, 例如 $$ 或 $body$; $1 等参数占位符不是标记
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// onlyComments 语句只包含注释
+func onlyComments(s string) bool {
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		switch {
+		case strings.HasPrefix(s, "--"):
+			s = s[skipUntil(s, 0, "\n"):]
+		case strings.HasPrefix(s, "/*"):
+			s = s[skipUntil(s, 2, "*/"):]
+		default:
+			return false
+		}
+	}
+	return true
+}
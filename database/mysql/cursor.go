@@ -0,0 +1,196 @@
+package mysql
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidCursor 游标格式错误或签名校验失败
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrCursorSecret 未配置游标签名密钥
+	ErrCursorSecret = errors.New("cursor secret not configured")
+)
+
+// Cursor 游标内容, 记录上一页边界行的排序键 (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`           // CreatedAt 边界行创建时间
+	ID        uint      `json:"i"`           // ID 边界行主键
+	Backward  bool      `json:"b,omitempty"` // Backward true 为向前(更新的数据)翻页
+}
+
+// CursorCodec 游标编解码, 游标为 base64(json).base64(hmac-sha256) 的不透明字符串
+type CursorCodec struct {
+	Secret []byte // Secret 签名密钥, 多实例部署时需保持一致
+}
+
+// CursorPage 游标分页结果
+type CursorPage[T any] struct {
+	List       []T    `json:"list"`
+	NextCursor string `json:"next_cursor,omitempty"` // NextCursor 下一页(更早的数据)游标, 为空表示没有更多
+	PrevCursor string `json:"prev_cursor,omitempty"` // PrevCursor 上一页(更新的数据)游标, 为空表示已是第一页
+}
+
+// Encode 编码并签名游标
+func (c *CursorCodec) Encode(cur *Cursor) (string, error) {
+	if len(c.Secret) == 0 {
+		return "", ErrCursorSecret
+	}
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode 校验签名并解码游标, 空字符串返回 nil 表示第一页
+func (c *CursorCodec) Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if len(c.Secret) == 0 {
+		return nil, ErrCursorSecret
+	}
+
+	enc := base64.RawURLEncoding
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	cur := &Cursor{}
+	if err = json.Unmarshal(payload, cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// sign hmac-sha256 签名
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// KeysetScope 游标分页 scope, 按 (created_at, id) 倒序; 多取一条用于判断是否还有更多数据
+// 向后翻页: WHERE (created_at, id) < (?, ?) ORDER BY created_at DESC, id DESC
+// 向前翻页: WHERE (created_at, id) > (?, ?) ORDER BY created_at ASC, id ASC, 结果需要反转
+func KeysetScope(cur *Cursor, limit int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		createdAt := clause.Column{Table: clause.CurrentTable, Name: "created_at"}
+		id := clause.Column{Table: clause.CurrentTable, Name: "id"}
+
+		desc := cur == nil || !cur.Backward
+		if cur != nil {
+			op := "<"
+			if !desc {
+				op = ">"
+			}
+			db = db.Where(clause.Expr{
+				SQL:  "(?, ?) " + op + " (?, ?)",
+				Vars: []interface{}{createdAt, id, cur.CreatedAt, cur.ID},
+			})
+		}
+
+		return db.Clauses(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: createdAt, Desc: desc},
+			{Column: id, Desc: desc},
+		}}).Limit(limit + 1)
+	}
+}
+
+// ListByCursor 游标分页查询, 适用于大表; ReqQuery.Cursor 为空时返回第一页, 关键字搜索与 List 一致, 忽略 Offset 和 Sort
+func (r *Repository[T]) ListByCursor(ctx context.Context, q *pkgs.ReqQuery, scopes ...func(*gorm.DB) *gorm.DB) (*CursorPage[T], error) {
+	if q == nil {
+		q = &pkgs.ReqQuery{}
+	}
+	codec := &CursorCodec{Secret: r.opts.CursorSecret}
+	cur, err := codec.Decode(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(codec.Secret) == 0 {
+		return nil, ErrCursorSecret
+	}
+
+	limit := r.limit(q)
+	list := make([]T, 0, limit+1)
+	tx := r.DB(ctx).Scopes(scopes...).Scopes(r.search(q.Key), KeysetScope(cur, limit))
+	if err = tx.Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(list) > limit
+	if hasMore {
+		list = list[:limit]
+	}
+	backward := cur != nil && cur.Backward
+	if backward {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	page := &CursorPage[T]{List: list}
+	if len(list) == 0 {
+		return page, nil
+	}
+
+	// 向后翻页: 有更多数据才有下一页, 非第一页才有上一页; 向前翻页: 总有下一页, 有更多数据才有上一页
+	if hasMore || backward {
+		if page.NextCursor, err = r.boundary(tx, codec, &list[len(list)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if (backward && hasMore) || (!backward && cur != nil) {
+		if page.PrevCursor, err = r.boundary(tx, codec, &list[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// boundary 根据边界行生成游标
+func (r *Repository[T]) boundary(tx *gorm.DB, codec *CursorCodec, row *T, backward bool) (string, error) {
+	s := tx.Statement.Schema
+	createdAtField, idField := s.LookUpField("created_at"), s.LookUpField("id")
+	if createdAtField == nil || idField == nil {
+		return "", fmt.Errorf("mysql: %s has no created_at/id column for cursor pagination", s.Name)
+	}
+
+	ctx, rv := tx.Statement.Context, reflect.ValueOf(row).Elem()
+	createdAt, _ := createdAtField.ValueOf(ctx, rv)
+	id, _ := idField.ValueOf(ctx, rv)
+
+	cur := &Cursor{Backward: backward}
+	var ok bool
+	if cur.CreatedAt, ok = createdAt.(time.Time); !ok {
+		return "", fmt.Errorf("mysql: %s.created_at is not time.Time", s.Name)
+	}
+	if cur.ID, ok = id.(uint); !ok {
+		return "", fmt.Errorf("mysql: %s.id is not uint", s.Name)
+	}
+	return codec.Encode(cur)
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigbigliu/go-core/pkgs"
+)
+
+func TestRepositoryListByCursor(t *testing.T) {
+	repo := NewRepository[repoUser](newTestDB(t), &RepositoryOptions{CursorSecret: []byte("secret")})
+	ctx := context.Background()
+
+	// 相同创建时间的记录按 id 区分先后
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		user := &repoUser{Name: string(rune('a' + i))}
+		user.CreatedAt = base.Add(time.Duration(i/2) * time.Hour)
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	names := func(list []repoUser) (s string) {
+		for _, u := range list {
+			s += u.Name
+		}
+		return
+	}
+
+	page1, err := repo.ListByCursor(ctx, &pkgs.ReqQuery{Limit: 2})
+	if err != nil || names(page1.List) != "ed" || page1.NextCursor == "" || page1.PrevCursor != "" {
+		t.Fatalf("第一页错误: %v %+v", err, page1)
+	}
+	page2, err := repo.ListByCursor(ctx, &pkgs.ReqQuery{Limit: 2, Cursor: page1.NextCursor})
+	if err != nil || names(page2.List) != "cb" || page2.NextCursor == "" || page2.PrevCursor == "" {
+		t.Fatalf("第二页错误: %v %+v", err, page2)
+	}
+	page3, err := repo.ListByCursor(ctx, &pkgs.ReqQuery{Limit: 2, Cursor: page2.NextCursor})
+	if err != nil || names(page3.List) != "a" || page3.NextCursor != "" {
+		t.Fatalf("最后一页错误: %v %+v", err, page3)
+	}
+
+	back, err := repo.ListByCursor(ctx, &pkgs.ReqQuery{Limit: 2, Cursor: page3.PrevCursor})
+	if err != nil || names(back.List) != "cb" || back.PrevCursor == "" || back.NextCursor == "" {
+		t.Fatalf("向前翻页错误: %v %+v", err, back)
+	}
+	first, err := repo.ListByCursor(ctx, &pkgs.ReqQuery{Limit: 2, Cursor: back.PrevCursor})
+	if err != nil || names(first.List) != "ed" || first.PrevCursor != "" {
+		t.Fatalf("回到第一页错误: %v %+v", err, first)
+	}
+
+	tampered := page1.NextCursor[:len(page1.NextCursor)-2] + "xx"
+	if _, err = repo.ListByCursor(ctx, &pkgs.ReqQuery{Cursor: tampered}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("篡改的游标应返回 ErrInvalidCursor, got %v", err)
+	}
+}
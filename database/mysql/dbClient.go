@@ -2,19 +2,21 @@ package mysql
 
 import (
 	"context"
-	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/bigbigliu/go-core/database"
+	"github.com/bigbigliu/go-core/database/migrate"
 	"github.com/bigbigliu/go-core/logger"
 	"go.uber.org/zap"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	gormLog "gorm.io/gorm/logger"
-	"os"
 )
 
 var (
-	// DBClient 全局DB客户端
+	// DBClient 全局DB客户端, 即 DefaultInstance 命名实例
 	DBClient *gorm.DB
-	err      error
 )
 
 // GenerateDSNParam 生成dsn参数
@@ -26,33 +28,105 @@ type GenerateDSNParam struct {
 	DbName string `json:"db_name"` // DbName 数据库名
 }
 
-// InitDB 初始化DB连接
+// Config mysql客户端配置, 其他驱动使用 database.Config
+type Config struct {
+	GenerateDSNParam
+	Name             string           `json:"name"`               // Name 实例名, 非空时注册为命名实例
+	MaxIdleConns     int              `json:"max_idle_conns"`     // MaxIdleConns 空闲连接池最大连接数, 默认10
+	MaxOpenConns     int              `json:"max_open_conns"`     // MaxOpenConns 最大打开连接数, 默认100
+	ConnMaxLifetime  time.Duration    `json:"conn_max_lifetime"`  // ConnMaxLifetime 连接最大复用时间, 0 不限制
+	ConnectTimeout   time.Duration    `json:"connect_timeout"`    // ConnectTimeout 初始连接最长等待时间, 默认30s
+	RetryInterval    time.Duration    `json:"retry_interval"`     // RetryInterval 初始连接重试间隔, 指数退避, 默认500ms
+	MaxRetryInterval time.Duration    `json:"max_retry_interval"` // MaxRetryInterval 重试间隔上限, 默认5s
+	LogLevel         gormLog.LogLevel `json:"log_level"`          // LogLevel gorm日志级别, 默认Info
+	DisableAudit     bool             `json:"disable_audit"`      // DisableAudit 关闭创建人/更新人/删除人自动填充
+	StatsInterval    time.Duration    `json:"stats_interval"`     // StatsInterval 连接池统计日志间隔, 0 不输出
+
+	Replicas            []Replica     `json:"replicas"`              // Replicas 只读副本, 为空时不启用读写分离
+	ReplicaPolicy       string        `json:"replica_policy"`        // ReplicaPolicy 副本选择策略 round_robin(默认)/weighted
+	HealthCheckInterval time.Duration `json:"health_check_interval"` // HealthCheckInterval 副本健康检查间隔, 默认10s
+
+	Migrations       fs.FS            `json:"-"`                 // Migrations 迁移文件目录, 非空时连接成功后执行待执行的迁移
+	MigrationOptions *migrate.Options `json:"migration_options"` // MigrationOptions 迁移配置, 为空时使用默认配置
+}
+
+// New 创建mysql客户端, 见 database.New
+func New(ctx context.Context, cfg *Config) (*gorm.DB, error) {
+	return database.New(ctx, cfg.databaseConfig())
+}
+
+// InitDB 初始化DB连接并写入 DBClient, 失败时退出进程; 需要自行处理错误时使用 New
 func (h *GenerateDSNParam) InitDB() {
-	logger.Logger.Info("DB", zap.String("conn", "connecting..."))
-	DBClient, err = gorm.Open(mysql.Open(
-		h.GenerateDSN()),
-		&gorm.Config{
-			Logger: logger.NewCustomLogger(logger.Logger, context.Background(), gormLog.Info),
-		})
+	h.InitDBWithMigrations(nil)
+}
+
+// InitDBWithMigrations 同 InitDB, migrations 非空时启动时执行待执行的迁移, 迁移失败同样退出进程
+//
+//	//go:embed migrations/*.sql
+//	var migrationsFS embed.FS
+//	sub, _ := fs.Sub(migrationsFS, "migrations")
+//	dbConf.InitDBWithMigrations(sub)
+func (h *GenerateDSNParam) InitDBWithMigrations(migrations fs.FS) {
+	db, err := New(context.Background(), &Config{GenerateDSNParam: *h, Name: DefaultInstance, Migrations: migrations})
 	if err != nil {
-		logger.Logger.Info("InitDB Error: ", zap.Error(err))
+		logger.Logger.Error("InitDB Error: ", zap.Error(err))
 		os.Exit(-1)
 	}
-	logger.Logger.Info("DB", zap.String("conn", "数据库连接成功"))
-	sqlDB, _ := DBClient.DB()
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	DBClient = db
 }
 
-// GenerateDSN dsn
+// GenerateDSN dsn, 包含明文密码, 禁止直接输出到日志
 func (h *GenerateDSNParam) GenerateDSN() string {
-	user := h.DbUser
-	pwd := h.DbPwd
-	dbmame := h.DbName
-	host := h.DbHost
-	port := h.DbPort
-
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", user, pwd, host, port, dbmame)
-	logger.Logger.Info("DB", zap.String("dsn", dsn))
-	return dsn
+	param := h.dsnParam()
+	return param.DSN()
+}
+
+// RedactedDSN 脱敏后的dsn, 用于日志输出 user:***@tcp(host:port)/db?...
+func (h *GenerateDSNParam) RedactedDSN() string {
+	param := h.dsnParam()
+	return param.RedactedDSN()
+}
+
+// String 实现 fmt.Stringer, 只输出脱敏后的连接信息
+func (h GenerateDSNParam) String() string {
+	return h.RedactedDSN()
+}
+
+// dsnParam 转换为通用dsn参数
+func (h *GenerateDSNParam) dsnParam() database.DSNParam {
+	return database.DSNParam{
+		Driver: database.DriverMySQL,
+		DbHost: h.DbHost,
+		DbPort: h.DbPort,
+		DbUser: h.DbUser,
+		DbPwd:  h.DbPwd,
+		DbName: h.DbName,
+	}
+}
+
+// databaseConfig 转换为通用客户端配置
+func (c *Config) databaseConfig() *database.Config {
+	replicas := make([]database.Replica, 0, len(c.Replicas))
+	for _, r := range c.Replicas {
+		replicas = append(replicas, database.Replica{DSNParam: r.dsnParam(), Weight: r.Weight})
+	}
+
+	return &database.Config{
+		DSNParam:            c.dsnParam(),
+		Name:                c.Name,
+		MaxIdleConns:        c.MaxIdleConns,
+		MaxOpenConns:        c.MaxOpenConns,
+		ConnMaxLifetime:     c.ConnMaxLifetime,
+		ConnectTimeout:      c.ConnectTimeout,
+		RetryInterval:       c.RetryInterval,
+		MaxRetryInterval:    c.MaxRetryInterval,
+		LogLevel:            c.LogLevel,
+		DisableAudit:        c.DisableAudit,
+		StatsInterval:       c.StatsInterval,
+		Replicas:            replicas,
+		ReplicaPolicy:       c.ReplicaPolicy,
+		HealthCheckInterval: c.HealthCheckInterval,
+		Migrations:          c.Migrations,
+		MigrationOptions:    c.MigrationOptions,
+	}
 }
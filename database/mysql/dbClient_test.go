@@ -0,0 +1,33 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactedDSN(t *testing.T) {
+	param := &GenerateDSNParam{
+		DbHost: "127.0.0.1",
+		DbPort: 3306,
+		DbUser: "root",
+		DbPwd:  "s3cret",
+		DbName: "core",
+	}
+
+	if dsn := param.GenerateDSN(); !strings.Contains(dsn, "root:s3cret@") {
+		t.Errorf("GenerateDSN 应包含明文密码: %s", dsn)
+	}
+
+	want := "root:***@tcp(127.0.0.1:3306)/core?charset=utf8mb4&parseTime=True&loc=Local"
+	if got := param.RedactedDSN(); got != want {
+		t.Errorf("RedactedDSN = %s, want %s", got, want)
+	}
+
+	// 值和指针格式化都不能泄露密码
+	for _, s := range []string{param.String(), fmt.Sprint(param), fmt.Sprintf("%v", *param)} {
+		if strings.Contains(s, "s3cret") {
+			t.Errorf("输出泄露了密码: %s", s)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/bigbigliu/go-core/database"
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultInstance 默认实例名, InitDB 使用该名称注册并同步到 DBClient
+	DefaultInstance = database.DefaultInstance
+
+	// ReplicaPolicyRoundRobin 轮询选择只读副本
+	ReplicaPolicyRoundRobin = database.ReplicaPolicyRoundRobin
+	// ReplicaPolicyWeighted 按权重平滑轮询选择只读副本
+	ReplicaPolicyWeighted = database.ReplicaPolicyWeighted
+)
+
+// Replica 只读副本配置, 未设置的用户名/密码/库名继承主库
+type Replica struct {
+	GenerateDSNParam
+	Weight int `json:"weight"` // Weight 权重, weighted 策略下生效, 默认1
+}
+
+// Register 注册命名DB实例, 见 database.Register
+func Register(name string, db *gorm.DB) {
+	database.Register(name, db)
+}
+
+// Get 获取命名DB实例, 见 database.Get
+func Get(name string) (*gorm.DB, bool) {
+	return database.Get(name)
+}
+
+// Close 关闭并移除命名DB实例, 见 database.Close
+func Close(name string) error {
+	return database.Close(name)
+}
+
+// WithPrimary 返回强制走主库的 ctx, 见 database.WithPrimary
+func WithPrimary(ctx context.Context) context.Context {
+	return database.WithPrimary(ctx)
+}
+
+// ForcePrimary gorm scope, 当前语句强制走主库, 见 database.ForcePrimary
+func ForcePrimary(db *gorm.DB) *gorm.DB {
+	return database.ForcePrimary(db)
+}
+
+// WithTx 在 DBClient 的事务中执行 fn, 见 database.WithTx
+func WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return database.WithTx(ctx, DBClient, fn)
+}
+
+// HealthChecker DBClient 的健康检查, 每次检查时读取 DBClient, 未初始化时返回 database.ErrNotInitialized
+func HealthChecker() pkgs.HealthChecker {
+	return database.NewHealthCheckerFunc(database.DriverMySQL, func() *gorm.DB { return DBClient })
+}
@@ -4,10 +4,21 @@ import (
 	"gorm.io/gorm"
 )
 
-// BasicModel 基础模型
+// BasicModel 基础模型, CreatedUser/UpdatedUser/DeletedUser 由 database 审计回调根据 ctx 中的用户自动填充
 type BasicModel struct {
 	gorm.Model
 	CreatedUser string `gorm:"column:created_user" json:"created_user"`
 	UpdatedUser string `gorm:"column:updated_user" json:"updated_user"`
 	DeletedUser string `gorm:"column:deleted_user" json:"deleted_user"`
 }
+
+// VersionedModel 带乐观锁的基础模型, 更新时校验并递增 Version, 记录已被并发修改时返回 *database.ConflictError
+type VersionedModel struct {
+	BasicModel
+	Version int64 `gorm:"column:version;not null;default:1;optimisticLock" json:"version"`
+}
+
+// Tenant 租户字段, 嵌入模型即启用租户隔离, 查询/更新/删除自动追加 tenant_id 条件, 新增自动填充
+type Tenant struct {
+	TenantID string `gorm:"column:tenant_id;size:64;not null;index" json:"tenant_id"`
+}
@@ -0,0 +1,257 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bigbigliu/go-core/database"
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageLimit = 20
+	defaultMaxLimit  = 100
+	likeEscape       = "!"
+)
+
+var (
+	// ErrInvalidSort 排序字段不在白名单中
+	ErrInvalidSort = errors.New("invalid sort field")
+)
+
+// RepositoryOptions 仓储配置
+type RepositoryOptions struct {
+	SearchFields []string // SearchFields ReqQuery.Key 模糊搜索的列名, 多列之间为 OR
+	SortFields   []string // SortFields 允许排序的列名白名单
+	DefaultSort  string   // DefaultSort 默认排序, 格式同 ReqQuery.Sort, 例如 "-id"
+	MaxLimit     int      // MaxLimit 单页最大条数, 默认100
+	CursorSecret []byte   // CursorSecret 游标签名密钥, ListByCursor 必须配置
+}
+
+// Repository 基于gorm的通用仓储, T 为模型类型(通常嵌入 BasicModel)
+type Repository[T any] struct {
+	db   *gorm.DB
+	opts RepositoryOptions
+}
+
+// NewRepository 创建仓储, opts 为 nil 时不支持关键字搜索和自定义排序
+func NewRepository[T any](db *gorm.DB, opts *RepositoryOptions) *Repository[T] {
+	r := &Repository[T]{db: db}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.MaxLimit <= 0 {
+		r.opts.MaxLimit = defaultMaxLimit
+	}
+	return r
+}
+
+// DB 当前仓储使用的 *gorm.DB, 已绑定 ctx 和模型; ctx 中存在事务时加入该事务
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return database.FromContext(ctx, r.db).Model(new(T))
+}
+
+// Get 按主键查询, 不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	entity := new(T)
+	if err := r.DB(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Take(entity).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// List 分页查询, 返回当前页数据和总数, 可直接填充 pkgs.ResultInfo 的 Data 和 Total
+func (r *Repository[T]) List(ctx context.Context, q *pkgs.ReqQuery, scopes ...func(*gorm.DB) *gorm.DB) ([]T, int64, error) {
+	if q == nil {
+		q = &pkgs.ReqQuery{}
+	}
+	orders, err := r.orderBy(q.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.Count(ctx, q, scopes...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]T, 0)
+	if total == 0 {
+		return list, 0, nil
+	}
+
+	tx := r.DB(ctx).Scopes(scopes...).Scopes(r.search(q.Key))
+	if len(orders) > 0 {
+		tx = tx.Clauses(clause.OrderBy{Columns: orders})
+	}
+	if err = tx.Offset(r.offset(q)).Limit(r.limit(q)).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// Count 按关键字和 scopes 统计总数
+func (r *Repository[T]) Count(ctx context.Context, q *pkgs.ReqQuery, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	var key string
+	if q != nil {
+		key = q.Key
+	}
+
+	var total int64
+	err := r.DB(ctx).Scopes(scopes...).Scopes(r.search(key)).Count(&total).Error
+	return total, err
+}
+
+// Create 新增
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return database.FromContext(ctx, r.db).Create(entity).Error
+}
+
+// Update 按主键更新, values 为 map 或结构体(只更新非零字段), 记录不存在时返回 gorm.ErrRecordNotFound;
+// 模型启用乐观锁(嵌入 VersionedModel)时 values 中带上读取时的 version 即校验乐观锁, 冲突时返回 *database.ConflictError.
+// mysql 默认按实际变更行计算影响行数, 更新为相同值时 RowsAffected 为0, 此时在主库确认记录是否存在
+func (r *Repository[T]) Update(ctx context.Context, id any, values any) error {
+	tx := r.DB(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Updates(values)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected > 0 {
+		return nil
+	}
+
+	var exists int64
+	err := r.DB(database.WithPrimary(ctx)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Limit(1).Count(&exists).Error
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SoftDelete 按主键软删除, 记录不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) SoftDelete(ctx context.Context, id any) error {
+	tx := database.FromContext(ctx, r.db).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore 恢复软删除的记录, 同时清空删除人, 记录不存在或未删除时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) Restore(ctx context.Context, id any) error {
+	tx := r.DB(ctx).Unscoped()
+	if err := tx.Statement.Parse(new(T)); err != nil {
+		return err
+	}
+
+	deletedAt := tx.Statement.Schema.LookUpField("DeletedAt")
+	if deletedAt == nil {
+		return fmt.Errorf("mysql: %s has no soft delete field", tx.Statement.Schema.Name)
+	}
+	values := map[string]interface{}{deletedAt.DBName: nil}
+	if field := tx.Statement.Schema.LookUpField(database.FieldDeletedUser); field != nil {
+		values[field.DBName] = ""
+	}
+
+	tx = tx.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		Where(clause.Neq{Column: clause.Column{Name: deletedAt.DBName}, Value: nil}).
+		Updates(values)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// search 关键字模糊搜索 scope, 对 LIKE 通配符做转义
+func (r *Repository[T]) search(key string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		key = strings.TrimSpace(key)
+		if key == "" || len(r.opts.SearchFields) == 0 {
+			return db
+		}
+
+		pattern := "%" + escapeLike(key) + "%"
+		exprs := make([]clause.Expression, 0, len(r.opts.SearchFields))
+		for _, field := range r.opts.SearchFields {
+			exprs = append(exprs, clause.Expr{
+				SQL:  "? LIKE ? ESCAPE '" + likeEscape + "'",
+				Vars: []interface{}{clause.Column{Name: field}, pattern},
+			})
+		}
+		return db.Where(clause.Or(exprs...))
+	}
+}
+
+// orderBy 解析排序参数 "-created_at,id", 只允许白名单中的列
+func (r *Repository[T]) orderBy(sort string) ([]clause.OrderByColumn, error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = r.opts.DefaultSort
+	}
+
+	var orders []clause.OrderByColumn
+	for _, item := range strings.Split(sort, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(item, "-")
+		name := strings.TrimLeft(item, "+-")
+		if !r.sortable(name) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSort, name)
+		}
+		orders = append(orders, clause.OrderByColumn{Column: clause.Column{Name: name}, Desc: desc})
+	}
+	return orders, nil
+}
+
+// sortable 是否允许排序, DefaultSort 中的列默认允许
+func (r *Repository[T]) sortable(name string) bool {
+	for _, f := range r.opts.SortFields {
+		if f == name {
+			return true
+		}
+	}
+	for _, item := range strings.Split(r.opts.DefaultSort, ",") {
+		if strings.TrimLeft(strings.TrimSpace(item), "+-") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// limit 每页条数, 默认20, 不超过 MaxLimit
+func (r *Repository[T]) limit(q *pkgs.ReqQuery) int {
+	if q.Limit <= 0 {
+		return defaultPageLimit
+	}
+	if q.Limit > r.opts.MaxLimit {
+		return r.opts.MaxLimit
+	}
+	return q.Limit
+}
+
+// offset 偏移量
+func (r *Repository[T]) offset(q *pkgs.ReqQuery) int {
+	if q.Offset < 0 {
+		return 0
+	}
+	return q.Offset
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bigbigliu/go-core/database"
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+type repoUser struct {
+	BasicModel
+	Name  string
+	Email string
+}
+
+// newTestDB 内存 sqlite, 仓储逻辑与驱动无关
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.New(context.Background(), &database.Config{
+		DSNParam:     database.DSNParam{Driver: database.DriverSQLite, DbName: testutil.SQLiteDSN(t)},
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	if err = db.AutoMigrate(&repoUser{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	return db
+}
+
+func TestRepositoryList(t *testing.T) {
+	repo := NewRepository[repoUser](newTestDB(t), &RepositoryOptions{
+		SearchFields: []string{"name", "email"},
+		SortFields:   []string{"name"},
+		DefaultSort:  "-id",
+	})
+	ctx := context.Background()
+
+	for _, name := range []string{"alice", "bob", "carol", "100%_off"} {
+		if err := repo.Create(ctx, &repoUser{Name: name, Email: name + "@example.com"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	list, total, err := repo.List(ctx, &pkgs.ReqQuery{Limit: 2})
+	if err != nil || total != 4 || len(list) != 2 || list[0].Name != "100%_off" {
+		t.Fatalf("默认排序分页错误: %v %d %+v", err, total, list)
+	}
+
+	list, total, err = repo.List(ctx, &pkgs.ReqQuery{Key: "%_", Sort: "name"})
+	if err != nil || total != 1 || list[0].Name != "100%_off" {
+		t.Fatalf("通配符未转义: %v %d %+v", err, total, list)
+	}
+
+	list, _, err = repo.List(ctx, &pkgs.ReqQuery{Key: "example", Sort: "name", Offset: 1, Limit: 1})
+	if err != nil || len(list) != 1 || list[0].Name != "alice" {
+		t.Fatalf("关键字搜索排序错误: %v %+v", err, list)
+	}
+
+	if _, _, err = repo.List(ctx, &pkgs.ReqQuery{Sort: "password"}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("非白名单排序字段应返回 ErrInvalidSort, got %v", err)
+	}
+}
+
+func TestRepositorySoftDeleteRestore(t *testing.T) {
+	repo := NewRepository[repoUser](newTestDB(t), nil)
+	ctx := database.WithUser(context.Background(), "admin")
+
+	user := &repoUser{Name: "dave"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Update(ctx, user.ID, map[string]interface{}{"email": "dave@example.com"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.SoftDelete(ctx, user.ID); err != nil {
+		t.Fatalf("SoftDelete: %v", err)
+	}
+	if _, err := repo.Get(ctx, user.ID); !pkgs.IsNoRowFoundError(err) {
+		t.Fatalf("软删除后 Get 应返回 not found, got %v", err)
+	}
+	if err := repo.SoftDelete(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("重复删除应返回 not found, got %v", err)
+	}
+
+	if err := repo.Restore(ctx, user.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	got, err := repo.Get(ctx, user.ID)
+	if err != nil || got.Email != "dave@example.com" || got.DeletedUser != "" || got.UpdatedUser != "admin" {
+		t.Fatalf("恢复后数据错误: %v %+v", err, got)
+	}
+	if err = repo.Restore(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("未删除的记录恢复应返回 not found, got %v", err)
+	}
+}
+
+func TestRepositoryUpdateUnchanged(t *testing.T) {
+	db := newTestDB(t)
+	// sqlite 按匹配行计算影响行数, 模拟 mysql 更新为相同值时 RowsAffected 为0
+	err := db.Callback().Update().After("gorm:update").Register("test:unchanged", func(tx *gorm.DB) {
+		tx.RowsAffected = 0
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	repo := NewRepository[repoUser](db, nil)
+	ctx := context.Background()
+
+	user := &repoUser{Name: "erin"}
+	if err = repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err = repo.Update(ctx, user.ID, map[string]interface{}{"name": "erin"}); err != nil {
+		t.Fatalf("记录存在时未变更不应返回错误, got %v", err)
+	}
+	if err = repo.Update(ctx, user.ID+1, map[string]interface{}{"name": "erin"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("记录不存在时应返回 not found, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// OptimisticLockTag 乐观锁版本号字段的 gorm 标签, 例如 `gorm:"optimisticLock"`, 字段需为整数类型;
+	// 嵌入 mysql.VersionedModel 即启用
+	OptimisticLockTag = "optimisticLock"
+
+	optimisticLockCallbackName = "go-core:optimistic_lock"
+	optimisticLockCheckName    = "go-core:optimistic_lock_check"
+	optimisticLockVersionKey   = "go-core:optimistic_lock:version"
+	optimisticLockSetKey       = "go-core:optimistic_lock:set"
+	optimisticLockFieldKey     = "go-core:optimistic_lock:field"
+)
+
+// ConflictError 乐观锁冲突, 记录已被其他请求修改; errors.Is(err, pkgs.ErrConflict) 为 true
+type ConflictError struct {
+	Table   string // Table 表名
+	Version int64  // Version 更新时期望的版本号
+}
+
+// Error 实现 error
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("database: %s version %d is stale, record was modified concurrently", e.Table, e.Version)
+}
+
+// Unwrap 便于 web 层按 pkgs.ErrConflict 统一转换为 409
+func (e *ConflictError) Unwrap() error {
+	return pkgs.ErrConflict
+}
+
+// RegisterOptimisticLockCallbacks 注册乐观锁回调, database.New 默认已注册; 对包含 optimisticLock 标签字段的模型:
+// 创建时版本号为零则置为1; 更新时追加 WHERE version = ? 并递增版本号, 影响行数为0时返回 *ConflictError.
+// 期望版本号依次取自 Updates 的 map/结构体中的 version, 以及 Model/Save 传入的已加载记录;
+// 都没有时只递增版本号不做校验, 使持有旧版本号的并发更新仍能检测到冲突. UpdateColumn 等跳过 hooks 的更新不做处理.
+// 结构体更新时在更新回调中生成 SET 子句, 其他修改更新字段的回调(如审计)需在此之前注册
+func RegisterOptimisticLockCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register(optimisticLockCallbackName, lockCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(optimisticLockCallbackName, lockBeforeUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Update().After("gorm:update").Register(optimisticLockCheckName, lockAfterUpdate)
+}
+
+// versionField 当前语句模型带 optimisticLock 标签的版本号字段, 非整数类型时返回错误
+func versionField(db *gorm.DB) (*schema.Field, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SkipHooks {
+		return nil, false
+	}
+	for _, field := range db.Statement.Schema.Fields {
+		if _, ok := field.TagSettings[strings.ToUpper(OptimisticLockTag)]; !ok {
+			continue
+		}
+		switch reflect.Indirect(reflect.New(field.FieldType)).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return field, true
+		}
+		_ = db.AddError(fmt.Errorf("database: optimistic lock field %s.%s must be an integer, got %s",
+			db.Statement.Schema.Name, field.Name, field.FieldType))
+		return nil, false
+	}
+	return nil, false
+}
+
+// lockCreate 创建时初始化版本号
+func lockCreate(db *gorm.DB) {
+	field, ok := versionField(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		setMapIfAbsent(dest, field, 1)
+	case []map[string]interface{}:
+		for _, m := range dest {
+			setMapIfAbsent(m, field, 1)
+		}
+	default:
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				setFieldIfZero(db, field, stmt.ReflectValue.Index(i), 1)
+			}
+		case reflect.Struct:
+			setFieldIfZero(db, field, stmt.ReflectValue, 1)
+		}
+	}
+}
+
+// lockBeforeUpdate 追加版本号条件并递增版本号
+func lockBeforeUpdate(db *gorm.DB) {
+	field, ok := versionField(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	expected, ok := expectedVersion(stmt, field)
+	if !ok {
+		incrVersion(db, field)
+		return
+	}
+
+	if dest, isMap := updateMap(db); isMap {
+		delete(dest, field.Name)
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: expected},
+	}})
+	stmt.SetColumn(field.DBName, expected+1, true)
+	db.InstanceSet(optimisticLockVersionKey, expected)
+	db.InstanceSet(optimisticLockFieldKey, field)
+}
+
+// incrVersion 未知版本号时只递增版本号. map 直接写入表达式; 结构体无法保存表达式,
+// 使用 gorm 导出的 ConvertToAssignments 生成 SET 子句后追加版本号递增, gorm:update 检测到已有 SET 子句时不再生成
+func incrVersion(db *gorm.DB, field *schema.Field) {
+	stmt := db.Statement
+	incr := gorm.Expr("? + 1", clause.Column{Name: field.DBName})
+	if dest, isMap := updateMap(db); isMap {
+		delete(dest, field.Name)
+		dest[field.DBName] = incr
+		return
+	}
+	if _, ok := stmt.Clauses["SET"]; ok {
+		return
+	}
+
+	set := callbacks.ConvertToAssignments(stmt)
+	if db.Error != nil || len(set) == 0 {
+		return
+	}
+	assignments := make(clause.Set, 0, len(set)+1)
+	for _, a := range set {
+		if a.Column.Name != field.DBName {
+			assignments = append(assignments, a)
+		}
+	}
+	stmt.AddClause(append(assignments, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: incr}))
+	db.InstanceSet(optimisticLockSetKey, true)
+}
+
+// lockAfterUpdate 影响行数为0时返回冲突错误, 并恢复内存中的版本号
+func lockAfterUpdate(db *gorm.DB) {
+	if _, ok := db.InstanceGet(optimisticLockSetKey); ok {
+		// 与 gorm:update 一致, 语句执行后删除生成的 SET 子句
+		delete(db.Statement.Clauses, "SET")
+	}
+
+	v, ok := db.InstanceGet(optimisticLockVersionKey)
+	if !ok || db.Error != nil || db.DryRun || db.RowsAffected > 0 {
+		return
+	}
+
+	field, _ := db.InstanceGet(optimisticLockFieldKey)
+	expected, stmt := v.(int64), db.Statement
+	stmt.SetColumn(field.(*schema.Field).DBName, expected, true)
+	if _, isMap := stmt.Dest.(map[string]interface{}); isMap && stmt.ReflectValue.Kind() == reflect.Struct && stmt.ReflectValue.CanAddr() {
+		_ = field.(*schema.Field).Set(stmt.Context, stmt.ReflectValue, expected)
+	}
+	_ = db.AddError(&ConflictError{Table: db.Statement.Table, Version: expected})
+}
+
+// expectedVersion 更新时期望的版本号, 未知时返回 false
+func expectedVersion(stmt *gorm.Statement, field *schema.Field) (int64, bool) {
+	if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+		for _, key := range []string{field.DBName, field.Name} {
+			if v, ok := dest[key]; ok {
+				return toVersion(v)
+			}
+		}
+	} else {
+		destValue := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+		if destValue.Kind() == reflect.Struct && destValue.Type() == stmt.Schema.ModelType {
+			if v, zero := field.ValueOf(stmt.Context, destValue); !zero {
+				return toVersion(v)
+			}
+		}
+	}
+
+	if stmt.ReflectValue.Kind() == reflect.Struct {
+		if v, zero := field.ValueOf(stmt.Context, stmt.ReflectValue); !zero {
+			return toVersion(v)
+		}
+	}
+	return 0, false
+}
+
+// toVersion 整数版本号转换为 int64
+func toVersion(v interface{}) (int64, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), rv.Uint() != 0
+	default:
+		return 0, false
+	}
+}
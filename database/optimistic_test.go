@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+type lockedItem struct {
+	gorm.Model
+	Name        string
+	UpdatedUser string
+	Version     int64 `gorm:"not null;default:1;optimisticLock"`
+}
+
+func TestOptimisticLock(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err = db.AutoMigrate(&lockedItem{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	item := &lockedItem{Name: "a"}
+	if err = db.Create(item).Error; err != nil || item.Version != 1 {
+		t.Fatalf("Create: %v, version = %d", err, item.Version)
+	}
+
+	// 两个请求读取同一版本
+	var first, second lockedItem
+	db.First(&first, item.ID)
+	db.First(&second, item.ID)
+
+	first.Name = "b"
+	if err = db.Save(&first).Error; err != nil || first.Version != 2 {
+		t.Fatalf("Save: %v, version = %d", err, first.Version)
+	}
+
+	err = db.Model(&second).Updates(map[string]interface{}{"name": "c"}).Error
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !pkgs.IsConflictError(err) || conflict.Version != 1 {
+		t.Fatalf("应返回冲突错误, got %v", err)
+	}
+	if second.Version != 1 {
+		t.Errorf("冲突后版本号应保持不变, got %d", second.Version)
+	}
+
+	// Save 冲突时不能退化为 upsert 覆盖
+	second.Name = "d"
+	if err = db.Save(&second).Error; !pkgs.IsConflictError(err) {
+		t.Fatalf("Save 应返回冲突错误, got %v", err)
+	}
+
+	// 显式传入版本号
+	values := map[string]interface{}{"name": "e", "version": 2}
+	if err = db.Model(&lockedItem{}).Where("id = ?", item.ID).Updates(values).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	if len(values) != 2 || values["version"] != 2 {
+		t.Fatalf("不应修改调用方的 map: %v", values)
+	}
+
+	// 未知版本号时只递增
+	if err = db.Model(&lockedItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{"name": "f"}).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+
+	// 结构体更新未带版本号时同样递增, 持有旧版本号的请求仍能检测到冲突
+	var stale lockedItem
+	db.First(&stale, item.ID)
+	if err = db.WithContext(WithUser(context.Background(), "alice")).Model(&lockedItem{}).Where("id = ?", item.ID).Updates(lockedItem{Name: "g"}).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	if err = db.Model(&stale).Updates(map[string]interface{}{"name": "h"}).Error; !pkgs.IsConflictError(err) {
+		t.Fatalf("结构体更新后旧版本号应冲突, got %v", err)
+	}
+
+	var got lockedItem
+	db.First(&got, item.ID)
+	if got.Name != "g" || got.Version != 5 || got.UpdatedUser != "alice" {
+		t.Errorf("got %+v, want name g version 5 updated by alice", got)
+	}
+	if pkgs.HTTPStatus(conflict) != 409 {
+		t.Errorf("冲突应转换为 409")
+	}
+}
+
+// apiDoc Version 为业务字段, 未启用乐观锁
+type apiDoc struct {
+	gorm.Model
+	Name    string
+	Version string
+}
+
+// badLockedItem 乐观锁字段不是整数
+type badLockedItem struct {
+	gorm.Model
+	Version string `gorm:"optimisticLock"`
+}
+
+func TestOptimisticLockOptIn(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err = db.AutoMigrate(&apiDoc{}, &badLockedItem{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	doc := &apiDoc{Name: "a", Version: "v2"}
+	if err = db.Create(doc).Error; err != nil || doc.Version != "v2" {
+		t.Fatalf("Create: %v, version = %q", err, doc.Version)
+	}
+	if err = db.Model(doc).Updates(map[string]interface{}{"name": "b"}).Error; err != nil {
+		t.Fatalf("未打标签的 Version 字段不应启用乐观锁: %v", err)
+	}
+	var got apiDoc
+	if db.First(&got, doc.ID); got.Version != "v2" || got.Name != "b" {
+		t.Fatalf("Version 字段不应被修改: %+v", got)
+	}
+
+	if err = db.Create(&badLockedItem{Version: "x"}).Error; err == nil || !strings.Contains(err.Error(), "must be an integer") {
+		t.Fatalf("非整数乐观锁字段应返回错误, got %v", err)
+	}
+}
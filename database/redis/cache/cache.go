@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis"
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultNotFoundTTL = time.Minute
+	defaultJitter      = 0.1
+)
+
+// notFoundMarker 负缓存标记, 记录不存在时写入, 避免缓存穿透
+var notFoundMarker = []byte("\x00go-core:not-found")
+
+// Options 缓存配置
+type Options struct {
+	Client      goRedis.UniversalClient // Client redis客户端, 为空时使用 redis.Redisclient; 都未初始化时只使用本地缓存
+	Serializer  Serializer              // Serializer 序列化方式, 默认 JSON
+	Prefix      string                  // Prefix key 前缀, 默认 redis.Key("cache") + ":", 即带命名空间的 "cache:"
+	NotFoundTTL time.Duration           // NotFoundTTL 负缓存时间, 默认1分钟, 小于0 关闭负缓存
+	Jitter      float64                 // Jitter ttl 随机增加的比例, 避免同时过期, 默认0.1, 小于0 关闭
+	LocalSize   int                     // LocalSize 本地 LRU 容量, 大于0 时启用本地+redis 两级缓存
+	LocalTTL    time.Duration           // LocalTTL 本地缓存时间, 默认与 ttl 相同; 多实例间本地缓存不同步, 建议设置较短时间
+}
+
+// Cache 旁路缓存, 未命中时通过 singleflight 合并并发加载
+type Cache struct {
+	opts  Options
+	group singleflight.Group
+	local *localLRU
+}
+
+// Default 默认缓存, 使用 redis.Redisclient 和 JSON 序列化
+var Default = New(nil)
+
+// New 创建缓存, opts 为 nil 时使用默认配置
+func New(opts *Options) *Cache {
+	c := &Cache{}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Serializer == nil {
+		c.opts.Serializer = JSON
+	}
+	if c.opts.NotFoundTTL == 0 {
+		c.opts.NotFoundTTL = defaultNotFoundTTL
+	}
+	if c.opts.Jitter == 0 {
+		c.opts.Jitter = defaultJitter
+	}
+	if c.opts.LocalSize > 0 {
+		c.local = newLocalLRU(c.opts.LocalSize)
+	}
+	return c
+}
+
+// GetOrLoad 使用 Default 缓存, 见 Load
+func GetOrLoad[T any](ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	return Load(Default, ctx, key, ttl, loader)
+}
+
+// Load 读取缓存, 未命中时调用 loader 加载并写入缓存; 同一实例内同一 key 的并发未命中只调用一次 loader.
+// loader 返回 pkgs.IsNotFoundError 判定的错误时写入负缓存, 之后在 NotFoundTTL 内直接返回 pkgs.ErrNotFound;
+// redis 不可用时降级为直接调用 loader
+func Load[T any](c *Cache, ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	key = c.key(key)
+
+	data, err := c.get(ctx, key)
+	if err == nil {
+		return decode[T](c, key, data)
+	}
+
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		// 双重检查, 避免排队期间其他调用已写入
+		if data, err := c.get(ctx, key); err == nil {
+			return data, nil
+		}
+
+		// 加载不受首个调用方取消的影响, 其他等待者仍可拿到结果
+		value, err := loader(context.WithoutCancel(ctx))
+		if err != nil {
+			if pkgs.IsNotFoundError(err) && c.opts.NotFoundTTL > 0 {
+				c.set(ctx, key, notFoundMarker, c.opts.NotFoundTTL)
+			}
+			return nil, err
+		}
+
+		data, err := c.opts.Serializer.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("cache: marshal %s: %w", key, err)
+		}
+		c.set(ctx, key, data, ttl)
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return decode[T](c, key, res.Val.([]byte))
+	}
+}
+
+// Set 直接写入缓存
+func Set[T any](c *Cache, ctx context.Context, key string, value T, ttl time.Duration) error {
+	data, err := c.opts.Serializer.Marshal(value)
+	if err != nil {
+		return err
+	}
+	key = c.key(key)
+	if c.local != nil {
+		c.local.set(key, data, c.localTTL(ttl))
+	}
+	client := c.client()
+	if client == nil {
+		return nil
+	}
+	return client.Set(ctx, key, data, c.jitter(ttl)).Err()
+}
+
+// Delete 删除缓存, 数据更新后调用; 本地缓存只能删除当前实例的数据
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	full := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = c.key(key)
+		if c.local != nil {
+			c.local.del(key)
+		}
+		full = append(full, key)
+	}
+	client := c.client()
+	if len(full) == 0 || client == nil {
+		return nil
+	}
+	return client.Del(ctx, full...).Err()
+}
+
+// get 依次读取本地缓存和redis, 命中redis时回填本地缓存
+func (c *Cache) get(ctx context.Context, key string) ([]byte, error) {
+	if c.local != nil {
+		if data, ok := c.local.get(key); ok {
+			return data, nil
+		}
+	}
+
+	client := c.client()
+	if client == nil {
+		return nil, goRedis.Nil
+	}
+	data, err := client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != goRedis.Nil {
+			logger.Logger.Warn("Cache", zap.String("key", key), zap.String("msg", "读取缓存失败, 降级为直接加载"), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	if c.local != nil {
+		ttl := c.opts.LocalTTL
+		if pttl, err := client.PTTL(ctx, key).Result(); err == nil && pttl > 0 && (ttl <= 0 || pttl < ttl) {
+			ttl = pttl
+		}
+		if ttl > 0 {
+			c.local.set(key, data, ttl)
+		}
+	}
+	return data, nil
+}
+
+// set 写入redis和本地缓存, 失败只记录日志
+func (c *Cache) set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if c.local != nil {
+		c.local.set(key, data, c.localTTL(ttl))
+	}
+	client := c.client()
+	if client == nil {
+		return
+	}
+	if err := client.Set(context.WithoutCancel(ctx), key, data, c.jitter(ttl)).Err(); err != nil {
+		logger.Logger.Warn("Cache", zap.String("key", key), zap.String("msg", "写入缓存失败"), zap.Error(err))
+	}
+}
+
+// key 完整 key; 未配置 Prefix 时在调用时拼接命名空间, 因为 Default 在 redis.SetNamespace 之前创建
+func (c *Cache) key(key string) string {
+	if c.opts.Prefix != "" {
+		return c.opts.Prefix + key
+	}
+	return redis.Key(redis.KeyCache, key)
+}
+
+// client redis客户端, 未配置且未调用 redis.InitRedis 时为 nil, 此时跳过 redis
+func (c *Cache) client() goRedis.UniversalClient {
+	if c.opts.Client != nil {
+		return c.opts.Client
+	}
+	return redis.Redisclient
+}
+
+// jitter ttl 随机增加 [0, Jitter) 比例
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if c.opts.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*c.opts.Jitter*float64(ttl))
+}
+
+// localTTL 本地缓存时间, 不超过 ttl
+func (c *Cache) localTTL(ttl time.Duration) time.Duration {
+	if c.opts.LocalTTL > 0 && c.opts.LocalTTL < ttl {
+		return c.opts.LocalTTL
+	}
+	return ttl
+}
+
+// decode 反序列化, 负缓存返回 pkgs.ErrNotFound
+func decode[T any](c *Cache, key string, data []byte) (T, error) {
+	var value T
+	if bytes.Equal(data, notFoundMarker) {
+		return value, fmt.Errorf("cache: %s: %w", key, pkgs.ErrNotFound)
+	}
+	if err := c.opts.Serializer.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("cache: unmarshal %s: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+type user struct {
+	ID   int    `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+// newTestCache 基于 miniredis 的缓存
+func newTestCache(t *testing.T, opts Options) (*miniredis.Miniredis, *Cache) {
+	t.Helper()
+	mr, client := testutil.NewRedis(t)
+	opts.Client = client
+	return mr, New(&opts)
+}
+
+func TestLoadSingleflight(t *testing.T) {
+	ctx := context.Background()
+	mr, c := newTestCache(t, Options{Serializer: Msgpack})
+
+	var calls int32
+	loader := func(ctx context.Context) (*user, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &user{ID: 1, Name: "alice"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u, err := Load(c, ctx, "user:1", time.Minute, loader)
+			if err != nil || u.Name != "alice" {
+				t.Errorf("Load: %v, %+v", err, u)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("并发未命中应只加载一次, calls = %d", calls)
+	}
+	if ttl := mr.TTL("cache:user:1"); ttl < time.Minute || ttl > 66*time.Second {
+		t.Fatalf("ttl 抖动超出范围: %v", ttl)
+	}
+
+	// 命中缓存
+	if _, err := Load(c, ctx, "user:1", time.Minute, loader); err != nil || calls != 1 {
+		t.Fatalf("应命中缓存: %v, calls = %d", err, calls)
+	}
+}
+
+func TestLoadNegativeCache(t *testing.T) {
+	ctx := context.Background()
+	_, c := newTestCache(t, Options{})
+
+	var calls int32
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{}, gorm.ErrRecordNotFound
+	}
+
+	if _, err := Load(c, ctx, "user:404", time.Minute, loader); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("应返回 loader 的错误, got %v", err)
+	}
+	_, err := Load(c, ctx, "user:404", time.Minute, loader)
+	if !errors.Is(err, pkgs.ErrNotFound) || !pkgs.IsNotFoundError(err) {
+		t.Fatalf("负缓存应返回 ErrNotFound, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("负缓存期间不应再次加载, calls = %d", calls)
+	}
+
+	// 其他错误不缓存
+	boom := errors.New("boom")
+	failing := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{}, boom
+	}
+	_, _ = Load(c, ctx, "user:500", time.Minute, failing)
+	if _, err = Load(c, ctx, "user:500", time.Minute, failing); !errors.Is(err, boom) || calls != 3 {
+		t.Fatalf("普通错误不应缓存: %v, calls = %d", err, calls)
+	}
+}
+
+func TestLoadTwoTier(t *testing.T) {
+	ctx := context.Background()
+	mr, c := newTestCache(t, Options{LocalSize: 2, LocalTTL: time.Minute})
+
+	loader := func(ctx context.Context) (user, error) {
+		return user{ID: 2, Name: "bob"}, nil
+	}
+	if _, err := Load(c, ctx, "user:2", time.Minute, loader); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// redis 数据丢失后本地缓存仍可命中
+	mr.FlushAll()
+	u, err := Load(c, ctx, "user:2", time.Minute, func(ctx context.Context) (user, error) {
+		return user{}, errors.New("不应调用")
+	})
+	if err != nil || u.Name != "bob" {
+		t.Fatalf("应命中本地缓存: %v, %+v", err, u)
+	}
+
+	if err = c.Delete(ctx, "user:2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := c.local.get("cache:user:2"); ok {
+		t.Fatalf("Delete 应删除本地缓存")
+	}
+
+	// LRU 淘汰
+	for _, key := range []string{"a", "b", "c"} {
+		_ = Set(c, ctx, key, user{Name: key}, time.Minute)
+	}
+	if _, ok := c.local.get("cache:a"); ok {
+		t.Fatalf("超出容量应淘汰最久未使用的数据")
+	}
+}
+
+func TestWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	// 未配置 Client 且未调用 redis.InitRedis
+	c := New(&Options{LocalSize: 4})
+
+	var calls int32
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{ID: 3, Name: "carol"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		if u, err := Load(c, ctx, "user:3", time.Minute, loader); err != nil || u.Name != "carol" {
+			t.Fatalf("Load: %v, %+v", err, u)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("应命中本地缓存, loader 调用次数 = %d", n)
+	}
+
+	if err := Set(c, ctx, "user:4", user{ID: 4}, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := c.Delete(ctx, "user:3", "user:4"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := Load(New(nil), ctx, "user:3", time.Minute, loader); err != nil {
+		t.Fatalf("没有本地缓存时应直接加载: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localEntry 本地缓存项
+type localEntry struct {
+	key      string
+	data     []byte
+	expireAt time.Time
+}
+
+// localLRU 带过期时间的进程内 LRU, 保存序列化后的数据, 避免调用方修改共享对象
+type localLRU struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// newLocalLRU 创建本地 LRU, size 为最大条数
+func newLocalLRU(size int) *localLRU {
+	return &localLRU{size: size, ll: list.New(), items: make(map[string]*list.Element, size)}
+}
+
+// get 获取未过期的数据
+func (l *localLRU) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*localEntry)
+	if time.Now().After(entry.expireAt) {
+		l.removeElement(elem)
+		return nil, false
+	}
+	l.ll.MoveToFront(elem)
+	return entry.data, true
+}
+
+// set 写入数据, 超出容量时淘汰最久未使用的数据
+func (l *localLRU) set(key string, data []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expireAt := time.Now().Add(ttl)
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*localEntry)
+		entry.data, entry.expireAt = data, expireAt
+		l.ll.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&localEntry{key: key, data: data, expireAt: expireAt})
+	for l.ll.Len() > l.size {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+// del 删除数据
+func (l *localLRU) del(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.items[key]; ok {
+		l.removeElement(elem)
+	}
+}
+
+// removeElement 调用方需持有锁
+func (l *localLRU) removeElement(elem *list.Element) {
+	l.ll.Remove(elem)
+	delete(l.items, elem.Value.(*localEntry).key)
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// JSON json 序列化, 默认
+	JSON Serializer = jsonSerializer{}
+	// Msgpack msgpack 序列化, 体积更小, 适合大对象
+	Msgpack Serializer = msgpackSerializer{}
+)
+
+// Serializer 缓存值序列化
+type Serializer interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// jsonSerializer json
+type jsonSerializer struct{}
+
+// Marshal 实现 Serializer
+func (jsonSerializer) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal 实现 Serializer
+func (jsonSerializer) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackSerializer msgpack
+type msgpackSerializer struct{}
+
+// Marshal 实现 Serializer
+func (msgpackSerializer) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal 实现 Serializer
+func (msgpackSerializer) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,93 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis"
+	"github.com/bigbigliu/go-core/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrClosed 事件总线已关闭
+	ErrClosed = errors.New("eventbus: closed")
+)
+
+// Event 事件, Data 为 json 编码的事件内容
+type Event struct {
+	ID          string          `json:"id"`
+	Topic       string          `json:"topic"`
+	Data        json.RawMessage `json:"data"`
+	RequestID   string          `json:"request_id,omitempty"` // RequestID 发布请求的 request ID, 用于串联日志
+	PublishedAt time.Time       `json:"published_at"`
+}
+
+// Bind 将事件内容反序列化到 v
+func (e *Event) Bind(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Handler 事件处理函数; Streams 实现中返回错误的事件不确认, 之后重新投递
+type Handler func(ctx context.Context, e *Event) error
+
+// Bus 事件总线, 实现有 PubSub(广播, 至多一次)、Streams(消费组, 至少一次)和 Memory(进程内, 用于测试)
+type Bus interface {
+	// Publish 发布事件, data 编码为 json
+	Publish(ctx context.Context, topic string, data any) error
+	// Subscribe 订阅 topic, 在后台接收事件直到 Close
+	Subscribe(topic string, h Handler) error
+	// Close 停止所有订阅
+	Close() error
+}
+
+// Subscribe 订阅 topic 并将事件内容解码为 T
+func Subscribe[T any](bus Bus, topic string, h func(ctx context.Context, v T) error) error {
+	return bus.Subscribe(topic, func(ctx context.Context, e *Event) error {
+		var v T
+		if err := e.Bind(&v); err != nil {
+			return fmt.Errorf("eventbus: decode %s: %w", e.Topic, err)
+		}
+		return h(ctx, v)
+	})
+}
+
+// newEvent 构造事件, 携带 ctx 中的 request ID
+func newEvent(ctx context.Context, topic string, data any) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("eventbus: marshal %s: %w", topic, err)
+	}
+	b := make([]byte, 12)
+	if _, err = rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	e := &Event{ID: hex.EncodeToString(b), Topic: topic, Data: raw, PublishedAt: time.Now()}
+	e.RequestID, _ = ctx.Value(logger.RequestIDKey).(string)
+	return e, nil
+}
+
+// dispatch 调用处理函数, panic 视为失败并记录日志
+func dispatch(ctx context.Context, h Handler, e *Event) (err error) {
+	ctx = context.WithValue(ctx, logger.RequestIDKey, e.RequestID)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("eventbus: handler panic: %v", r)
+		}
+		if err != nil {
+			logger.Logger.WithOptions(logger.WithContext(ctx)).Warn("EventBus", zap.String("topic", e.Topic), zap.String("event_id", e.ID), zap.Error(err))
+		}
+	}()
+	return h(ctx, e)
+}
+
+// topicKey topic 对应的 redis channel/stream key
+func topicKey(topic string) string {
+	return redis.Key(redis.KeyEvent, topic)
+}
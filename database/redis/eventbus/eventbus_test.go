@@ -0,0 +1,186 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/logger"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+type invalidate struct {
+	Key string `json:"key"`
+}
+
+// receiveOne 等待一个事件
+func receiveOne(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("未收到事件")
+		return ""
+	}
+}
+
+func TestMemory(t *testing.T) {
+	bus := NewMemory()
+	got := make(chan string, 2)
+	_ = Subscribe(bus, "cache", func(ctx context.Context, v invalidate) error {
+		requestID, _ := ctx.Value(logger.RequestIDKey).(string)
+		got <- v.Key + "|" + requestID
+		return nil
+	})
+	_ = bus.Subscribe("cache", func(ctx context.Context, e *Event) error {
+		panic("boom")
+	})
+
+	ctx := context.WithValue(context.Background(), logger.RequestIDKey, "req-1")
+	if err := bus.Publish(ctx, "cache", invalidate{Key: "user:1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if v := receiveOne(t, got); v != "user:1|req-1" {
+		t.Fatalf("got %q", v)
+	}
+
+	_ = bus.Close()
+	if err := bus.Publish(ctx, "cache", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("关闭后发布应返回 ErrClosed, got %v", err)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	_, client := testutil.NewRedis(t)
+
+	// 两个实例都应收到广播
+	got := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		bus := NewPubSub(&Options{Client: client})
+		t.Cleanup(func() { _ = bus.Close() })
+		if err := Subscribe(bus, "config", func(ctx context.Context, v invalidate) error {
+			got <- v.Key
+			return nil
+		}); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+	}
+
+	pub := NewPubSub(&Options{Client: client})
+	if err := pub.Publish(context.Background(), "config", invalidate{Key: "reload"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if v := receiveOne(t, got); v != "reload" {
+			t.Fatalf("got %q", v)
+		}
+	}
+}
+
+func TestPubSubResubscribe(t *testing.T) {
+	mr, client := testutil.NewRedis(t)
+
+	bus := NewPubSub(&Options{Client: client})
+	defer bus.Close()
+	got := make(chan string, 1)
+	_ = Subscribe(bus, "config", func(ctx context.Context, v invalidate) error {
+		got <- v.Key
+		return nil
+	})
+
+	// 模拟连接断开
+	mr.Restart()
+	for i := 0; i < 100; i++ {
+		if n, _ := client.PubSubNumSub(context.Background(), topicKey("config")).Result(); n[topicKey("config")] > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	_ = bus.Publish(context.Background(), "config", invalidate{Key: "after-restart"})
+	if v := receiveOne(t, got); v != "after-restart" {
+		t.Fatalf("got %q", v)
+	}
+}
+
+func TestStreamsConsumerGroup(t *testing.T) {
+	_, client := testutil.NewRedis(t)
+
+	var count int32
+	got := make(chan string, 10)
+	for _, consumer := range []string{"a", "b"} {
+		bus, err := NewStreams(&Options{Client: client, Group: "workers", Consumer: consumer, Block: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewStreams: %v", err)
+		}
+		t.Cleanup(func() { _ = bus.Close() })
+		_ = Subscribe(bus, "revoke", func(ctx context.Context, v invalidate) error {
+			atomic.AddInt32(&count, 1)
+			got <- v.Key
+			return nil
+		})
+	}
+
+	pub, _ := NewStreams(&Options{Client: client, Group: "publisher"})
+	for _, key := range []string{"t1", "t2", "t3"} {
+		if err := pub.Publish(context.Background(), "revoke", invalidate{Key: key}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		receiveOne(t, got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&count); n != 3 {
+		t.Fatalf("同一消费组内每个事件只处理一次, got %d", n)
+	}
+
+	if _, err := NewStreams(&Options{Client: client}); !errors.Is(err, ErrMissingGroup) {
+		t.Fatalf("未配置消费组应返回 ErrMissingGroup, got %v", err)
+	}
+}
+
+func TestStreamsRedelivery(t *testing.T) {
+	_, client := testutil.NewRedis(t)
+
+	// 消费者 a 处理失败, 事件超时未确认后由 b 接管
+	failing, _ := NewStreams(&Options{Client: client, Group: "g", Consumer: "a", Block: 20 * time.Millisecond, ClaimIdle: time.Hour})
+	attempted := make(chan struct{}, 1)
+	_ = failing.Subscribe("job", func(ctx context.Context, e *Event) error {
+		select {
+		case attempted <- struct{}{}:
+		default:
+		}
+		return errors.New("boom")
+	})
+
+	if err := failing.Publish(context.Background(), "job", invalidate{Key: "k"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	<-attempted
+	_ = failing.Close()
+
+	ok, _ := NewStreams(&Options{Client: client, Group: "g", Consumer: "b", Block: 20 * time.Millisecond, ClaimIdle: 50 * time.Millisecond})
+	defer ok.Close()
+	got := make(chan string, 1)
+	_ = Subscribe(ok, "job", func(ctx context.Context, v invalidate) error {
+		got <- v.Key
+		return nil
+	})
+	if v := receiveOne(t, got); v != "k" {
+		t.Fatalf("got %q", v)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	pending, err := client.XPending(context.Background(), topicKey("job"), "g").Result()
+	if err != nil || pending.Count != 0 {
+		t.Fatalf("处理成功后应确认: %v, %+v", err, pending)
+	}
+}
@@ -0,0 +1,59 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory 进程内事件总线, Publish 同步调用所有订阅者, 用于单元测试和单实例部署
+type Memory struct {
+	mu       sync.RWMutex
+	closed   bool
+	handlers map[string][]Handler
+}
+
+// NewMemory 创建进程内事件总线
+func NewMemory() *Memory {
+	return &Memory{handlers: make(map[string][]Handler)}
+}
+
+// Publish 实现 Bus, 处理函数的错误只记录日志
+func (m *Memory) Publish(ctx context.Context, topic string, data any) error {
+	e, err := newEvent(ctx, topic, data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	handlers := m.handlers[topic]
+	m.mu.RUnlock()
+
+	for _, h := range handlers {
+		_ = dispatch(ctx, h, e)
+	}
+	return nil
+}
+
+// Subscribe 实现 Bus
+func (m *Memory) Subscribe(topic string, h Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.handlers[topic] = append(m.handlers[topic], h)
+	return nil
+}
+
+// Close 实现 Bus
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.handlers = nil
+	return nil
+}
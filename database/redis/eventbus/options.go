@@ -0,0 +1,69 @@
+package eventbus
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis"
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultMaxLen            = 10000
+	defaultClaimIdle         = time.Minute
+	defaultBlock             = time.Second
+	defaultReconnectInterval = time.Second
+	maxReconnectInterval     = 30 * time.Second
+)
+
+// Options redis 事件总线配置
+type Options struct {
+	Client            goRedis.UniversalClient // Client redis客户端, 为空时使用 redis.Redisclient
+	Group             string                  // Group Streams 消费组名称, 同组实例分摊事件, 不同组各自收到全部事件; Streams 必填
+	Consumer          string                  // Consumer Streams 消费者名称, 默认 hostname-pid
+	MaxLen            int64                   // MaxLen Streams 保留的最大事件数(近似), 默认10000
+	ClaimIdle         time.Duration           // ClaimIdle Streams 未确认事件超过该时间后由其他消费者接管, 默认1分钟
+	Block             time.Duration           // Block Streams 每次阻塞读取的时间, 也是 Close 的最长等待时间, 默认1秒
+	ReconnectInterval time.Duration           // ReconnectInterval 连接断开后重新订阅的初始间隔, 指数退避至30秒, 默认1秒
+}
+
+// withDefaults 填充默认配置
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.Client == nil {
+		opts.Client = redis.Redisclient
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = defaultMaxLen
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = defaultClaimIdle
+	}
+	if opts.Block <= 0 {
+		opts.Block = defaultBlock
+	}
+	if opts.ReconnectInterval <= 0 {
+		opts.ReconnectInterval = defaultReconnectInterval
+	}
+	return opts
+}
+
+// backoff 重新订阅的等待时间
+func (o *Options) backoff(failures int) time.Duration {
+	d := o.ReconnectInterval
+	for i := 1; i < failures && d < maxReconnectInterval; i++ {
+		d *= 2
+	}
+	if d > maxReconnectInterval {
+		d = maxReconnectInterval
+	}
+	return d
+}
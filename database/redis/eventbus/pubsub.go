@@ -0,0 +1,107 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// PubSub 基于 redis Pub/Sub 的事件总线, 所有在线订阅者都会收到事件, 离线期间的事件丢失(至多一次),
+// 适合缓存失效、配置重载等可丢失的广播通知
+type PubSub struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewPubSub 创建 Pub/Sub 事件总线, opts 为 nil 时使用默认配置
+func NewPubSub(opts *Options) *PubSub {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PubSub{opts: opts.withDefaults(), ctx: ctx, cancel: cancel}
+}
+
+// Publish 实现 Bus
+func (p *PubSub) Publish(ctx context.Context, topic string, data any) error {
+	if p.ctx.Err() != nil {
+		return ErrClosed
+	}
+	e, err := newEvent(ctx, topic, data)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return p.opts.Client.Publish(ctx, topicKey(topic), raw).Err()
+}
+
+// Subscribe 实现 Bus, 连接断开后自动重新订阅
+func (p *PubSub) Subscribe(topic string, h Handler) error {
+	if p.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	// 首次订阅同步完成, 保证返回后发布的事件能收到
+	sub := p.opts.Client.Subscribe(p.ctx, topicKey(topic))
+	if _, err := sub.Receive(p.ctx); err != nil {
+		_ = sub.Close()
+		return err
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.receive(topic, sub, h)
+	}()
+	return nil
+}
+
+// Close 实现 Bus, 等待处理中的事件完成
+func (p *PubSub) Close() error {
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+// receive 接收事件直到关闭; go-redis 在连接断开时自动重连并重新订阅,
+// 订阅被意外关闭时按退避间隔重新订阅
+func (p *PubSub) receive(topic string, sub *goRedis.PubSub, h Handler) {
+	failures := 0
+	ch := sub.Channel()
+	for {
+		select {
+		case <-p.ctx.Done():
+			_ = sub.Close()
+			return
+		case msg, ok := <-ch:
+			if ok {
+				failures = 0
+				var e Event
+				if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+					logger.Logger.Warn("EventBus", zap.String("topic", topic), zap.String("msg", "事件解码失败"), zap.Error(err))
+					continue
+				}
+				_ = dispatch(p.ctx, h, &e)
+				continue
+			}
+
+			failures++
+			wait := p.opts.backoff(failures)
+			logger.Logger.Warn("EventBus", zap.String("topic", topic), zap.String("msg", "订阅已关闭, 稍后重新订阅"), zap.Duration("wait", wait))
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			sub = p.opts.Client.Subscribe(p.ctx, topicKey(topic))
+			ch = sub.Channel()
+		}
+	}
+}
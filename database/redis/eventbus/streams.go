@@ -0,0 +1,215 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	streamField = "event"
+	readCount   = 10
+	claimScan   = 100
+)
+
+// ErrMissingGroup Streams 事件总线未配置消费组
+var ErrMissingGroup = errors.New("eventbus: streams consumer group is required")
+
+// Streams 基于 redis Streams 消费组的事件总线, 同一消费组内每个事件只由一个消费者处理,
+// 处理成功后确认, 失败或消费者宕机的事件在 ClaimIdle 后重新投递(至少一次), 处理函数需要幂等
+type Streams struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewStreams 创建 Streams 事件总线, Group 必填
+func NewStreams(opts *Options) (*Streams, error) {
+	o := opts.withDefaults()
+	if o.Group == "" {
+		return nil, ErrMissingGroup
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Streams{opts: o, ctx: ctx, cancel: cancel}, nil
+}
+
+// Publish 实现 Bus, stream 按 MaxLen 近似裁剪
+func (s *Streams) Publish(ctx context.Context, topic string, data any) error {
+	if s.ctx.Err() != nil {
+		return ErrClosed
+	}
+	e, err := newEvent(ctx, topic, data)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.opts.Client.XAdd(ctx, &goRedis.XAddArgs{
+		Stream: topicKey(topic),
+		MaxLen: s.opts.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{streamField: raw},
+	}).Err()
+}
+
+// Subscribe 实现 Bus, 消费组不存在时创建并从 stream 起始位置消费
+func (s *Streams) Subscribe(topic string, h Handler) error {
+	if s.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if err := s.createGroup(topic); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.consume(topic, h)
+	}()
+	return nil
+}
+
+// Close 实现 Bus, 最长等待 Block 时间以及处理中的事件完成
+func (s *Streams) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// createGroup 创建消费组, 已存在时忽略
+func (s *Streams) createGroup(topic string) error {
+	err := s.opts.Client.XGroupCreateMkStream(s.ctx, topicKey(topic), s.opts.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// consume 循环接管超时未确认的事件并读取新事件, 出错时按退避间隔重试
+func (s *Streams) consume(topic string, h Handler) {
+	key := topicKey(topic)
+	failures := 0
+	nextClaim := time.Now()
+
+	for s.ctx.Err() == nil {
+		var (
+			msgs []goRedis.XMessage
+			err  error
+		)
+		if !time.Now().Before(nextClaim) {
+			msgs, err = s.claim(key)
+			nextClaim = time.Now().Add(s.opts.ClaimIdle / 2)
+		}
+		if err == nil && len(msgs) == 0 {
+			msgs, err = s.read(key)
+		}
+
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			// stream 被删除后消费组随之消失, 重新创建
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				err = s.createGroup(topic)
+			}
+			if err != nil {
+				failures++
+				wait := s.opts.backoff(failures)
+				logger.Logger.Warn("EventBus", zap.String("topic", topic), zap.String("msg", "读取事件失败, 稍后重试"), zap.Duration("wait", wait), zap.Error(err))
+				select {
+				case <-s.ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+			continue
+		}
+		failures = 0
+
+		for _, msg := range msgs {
+			s.handle(key, topic, msg, h)
+		}
+	}
+}
+
+// read 读取新事件, 阻塞超时返回空
+func (s *Streams) read(key string) ([]goRedis.XMessage, error) {
+	streams, err := s.opts.Client.XReadGroup(s.ctx, &goRedis.XReadGroupArgs{
+		Group:    s.opts.Group,
+		Consumer: s.opts.Consumer,
+		Streams:  []string{key, ">"},
+		Count:    readCount,
+		Block:    s.opts.Block,
+	}).Result()
+	if err == goRedis.Nil {
+		return nil, nil
+	}
+	if err != nil || len(streams) == 0 {
+		return nil, err
+	}
+	return streams[0].Messages, nil
+}
+
+// claim 接管其他消费者超过 ClaimIdle 未确认的事件; 使用 XPENDING + XCLAIM 以兼容 redis 5.0
+func (s *Streams) claim(key string) ([]goRedis.XMessage, error) {
+	pending, err := s.opts.Client.XPendingExt(s.ctx, &goRedis.XPendingExtArgs{
+		Stream: key,
+		Group:  s.opts.Group,
+		Start:  "-",
+		End:    "+",
+		Count:  claimScan,
+	}).Result()
+	if err == goRedis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, readCount)
+	for _, p := range pending {
+		if p.Idle >= s.opts.ClaimIdle {
+			ids = append(ids, p.ID)
+		}
+		if len(ids) == readCount {
+			break
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// XCLAIM 再次校验空闲时间, 并发接管时只有一个消费者成功
+	return s.opts.Client.XClaim(s.ctx, &goRedis.XClaimArgs{
+		Stream:   key,
+		Group:    s.opts.Group,
+		Consumer: s.opts.Consumer,
+		MinIdle:  s.opts.ClaimIdle,
+		Messages: ids,
+	}).Result()
+}
+
+// handle 处理事件, 成功或事件无法解码时确认, 失败时保留在 pending 中等待重新投递
+func (s *Streams) handle(key, topic string, msg goRedis.XMessage, h Handler) {
+	var e Event
+	raw, _ := msg.Values[streamField].(string)
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		logger.Logger.Warn("EventBus", zap.String("topic", topic), zap.String("stream_id", msg.ID), zap.String("msg", "事件解码失败, 丢弃"), zap.Error(err))
+	} else if err = dispatch(s.ctx, h, &e); err != nil {
+		return
+	}
+
+	if err := s.opts.Client.XAck(context.WithoutCancel(s.ctx), key, s.opts.Group, msg.ID).Err(); err != nil {
+		logger.Logger.Warn("EventBus", zap.String("topic", topic), zap.String("stream_id", msg.ID), zap.Error(err))
+	}
+}
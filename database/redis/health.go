@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+const (
+	// healthName redis 健康检查名称
+	healthName = "redis"
+)
+
+// ErrNotInitialized redis 客户端尚未初始化
+var ErrNotInitialized = errors.New("redis: client not initialized")
+
+// healthChecker redis 健康检查
+type healthChecker struct {
+	name   string
+	client func() goRedis.UniversalClient // client 每次检查时获取客户端, 兼容先注册检查、后初始化的 Redisclient
+}
+
+// NewHealthChecker 创建 redis 健康检查
+func NewHealthChecker(name string, client goRedis.UniversalClient) pkgs.HealthChecker {
+	return &healthChecker{name: name, client: func() goRedis.UniversalClient { return client }}
+}
+
+// HealthChecker Redisclient 的健康检查, 每次检查时读取 Redisclient, 未初始化时返回 ErrNotInitialized
+func HealthChecker() pkgs.HealthChecker {
+	return &healthChecker{name: healthName, client: func() goRedis.UniversalClient { return Redisclient }}
+}
+
+// Name 依赖名称
+func (h *healthChecker) Name() string {
+	return h.name
+}
+
+// Check ping, 客户端未初始化时返回 ErrNotInitialized
+func (h *healthChecker) Check(ctx context.Context) error {
+	client := h.client()
+	if client == nil {
+		return ErrNotInitialized
+	}
+	return client.Ping(ctx).Err()
+}
+
+// Stats 连接池统计, 集群模式为所有节点之和; 客户端未初始化时为空
+func (h *healthChecker) Stats() pkgs.PoolStats {
+	client := h.client()
+	if client == nil {
+		return pkgs.PoolStats{}
+	}
+	s := client.PoolStats()
+	stats := pkgs.PoolStats{
+		Open:     int(s.TotalConns),
+		Idle:     int(s.IdleConns),
+		InUse:    int(s.TotalConns - s.IdleConns),
+		Timeouts: int64(s.Timeouts),
+	}
+	switch c := client.(type) {
+	case *goRedis.Client:
+		stats.MaxOpen = c.Options().PoolSize
+	case *goRedis.ClusterClient:
+		stats.MaxOpen = c.Options().PoolSize * len(c.Options().Addrs)
+	}
+	return stats
+}
@@ -0,0 +1,29 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+)
+
+func TestHealthCheckerResolvesClient(t *testing.T) {
+	saved := Redisclient
+	t.Cleanup(func() { Redisclient = saved })
+
+	// 先注册检查, 后初始化客户端
+	Redisclient = nil
+	checker := HealthChecker()
+	if err := checker.Check(context.Background()); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("未初始化时应返回 ErrNotInitialized, got %v", err)
+	}
+	if stats := checker.Stats(); stats.Open != 0 {
+		t.Fatalf("未初始化时统计应为空: %+v", stats)
+	}
+
+	_, Redisclient = testutil.NewRedis(t)
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("初始化后检查失败: %v", err)
+	}
+}
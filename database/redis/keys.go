@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+// go-core 组件使用的 key 前缀, 完整 key 为 [namespace:]component:...
+const (
+	KeyCaptcha = "captcha"     // KeyCaptcha 验证码
+	KeyIPLimit = "ip_requests" // KeyIPLimit IP 限流计数
+	KeyToken   = "coreos"      // KeyToken jwt token
+	KeyLock    = "lock"        // KeyLock 分布式锁
+	KeyCache   = "cache"       // KeyCache 旁路缓存
+	KeyQueue   = "queue"       // KeyQueue 任务队列
+	KeyEvent   = "event"       // KeyEvent 事件总线
+
+	keySeparator = ":"
+	scanCount    = 500
+)
+
+var (
+	namespaceMu sync.RWMutex
+	namespace   string
+)
+
+// SetNamespace 设置应用级 key 命名空间, 多个应用共用 redis 时避免 key 冲突; InitRedis 按配置自动设置.
+// 命名空间不能包含 '{' '}', 以免影响集群模式的 hash tag
+func SetNamespace(ns string) error {
+	if strings.ContainsAny(ns, "{}") {
+		return fmt.Errorf("redis: invalid namespace %q", ns)
+	}
+	namespaceMu.Lock()
+	namespace = strings.TrimSuffix(ns, keySeparator)
+	namespaceMu.Unlock()
+	return nil
+}
+
+// Namespace 当前命名空间, 未设置时为空
+func Namespace() string {
+	namespaceMu.RLock()
+	defer namespaceMu.RUnlock()
+	return namespace
+}
+
+// Key 拼接带命名空间的 key, 例如命名空间为 app 时 Key("lock", "order") 返回 "app:lock:order"
+func Key(parts ...string) string {
+	if ns := Namespace(); ns != "" {
+		parts = append([]string{ns}, parts...)
+	}
+	return strings.Join(parts, keySeparator)
+}
+
+// KeyPattern 命名空间下 component 的 SCAN 匹配模式, component 为空时匹配整个命名空间
+func KeyPattern(component string) string {
+	if component == "" {
+		if ns := Namespace(); ns != "" {
+			return ns + keySeparator + "*"
+		}
+		return "*"
+	}
+	return Key(component) + keySeparator + "*"
+}
+
+// ScanKeys 使用 SCAN 遍历匹配 pattern 的 key, 集群模式遍历所有主节点; fn 返回错误时停止
+func ScanKeys(ctx context.Context, client goRedis.UniversalClient, pattern string, fn func(keys []string) error) error {
+	if cluster, ok := client.(*goRedis.ClusterClient); ok {
+		var mu sync.Mutex
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *goRedis.Client) error {
+			return scanNode(ctx, node, pattern, func(keys []string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				return fn(keys)
+			})
+		})
+	}
+	return scanNode(ctx, client, pattern, fn)
+}
+
+// ListKeys 返回匹配 pattern 的 key, limit 大于0 时最多返回 limit 个
+func ListKeys(ctx context.Context, client goRedis.UniversalClient, pattern string, limit int) ([]string, error) {
+	var (
+		result []string
+		errEnd = fmt.Errorf("redis: scan limit reached")
+	)
+	err := ScanKeys(ctx, client, pattern, func(keys []string) error {
+		for _, key := range keys {
+			if limit > 0 && len(result) >= limit {
+				return errEnd
+			}
+			result = append(result, key)
+		}
+		return nil
+	})
+	if err == errEnd {
+		err = nil
+	}
+	return result, err
+}
+
+// PurgeKeys 删除匹配 pattern 的 key, 返回删除数量; 逐个删除以兼容集群模式的跨 slot 限制
+func PurgeKeys(ctx context.Context, client goRedis.UniversalClient, pattern string) (int64, error) {
+	var deleted int64
+	err := ScanKeys(ctx, client, pattern, func(keys []string) error {
+		pipe := client.Pipeline()
+		cmds := make([]*goRedis.IntCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Del(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			deleted += cmd.Val()
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// scanNode 遍历单个节点
+func scanNode(ctx context.Context, client goRedis.Cmdable, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+// RunKeys 执行 key 管理子命令, 作用范围为当前命名空间, 便于服务在自己的命令行中挂载:
+//
+//	list [component] [n]              列出 key, n 为最大数量, 默认全部
+//	count [component]                 统计 key 数量
+//	purge [component] [--dry-run]     删除 key; 未设置命名空间时必须指定 component
+func RunKeys(ctx context.Context, client goRedis.UniversalClient, args []string, out io.Writer) error {
+	var (
+		cmd, component string
+		limit          int
+		dryRun         bool
+	)
+	for _, arg := range args {
+		switch {
+		case arg == "--dry-run":
+			dryRun = true
+		case cmd == "":
+			cmd = arg
+		case component == "":
+			component = arg
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("redis: invalid limit %q", arg)
+			}
+			limit = n
+		}
+	}
+	pattern := KeyPattern(component)
+
+	switch cmd {
+	case "list":
+		keys, err := ListKeys(ctx, client, pattern, limit)
+		for _, key := range keys {
+			fmt.Fprintln(out, key)
+		}
+		return err
+	case "count":
+		var n int
+		err := ScanKeys(ctx, client, pattern, func(keys []string) error {
+			n += len(keys)
+			return nil
+		})
+		fmt.Fprintf(out, "%s\t%d\n", pattern, n)
+		return err
+	case "purge":
+		if component == "" && Namespace() == "" {
+			return fmt.Errorf("redis: purge without namespace requires a component")
+		}
+		if dryRun {
+			keys, err := ListKeys(ctx, client, pattern, 0)
+			fmt.Fprintf(out, "[dry-run] purge %s: %d keys\n", pattern, len(keys))
+			return err
+		}
+		n, err := PurgeKeys(ctx, client, pattern)
+		fmt.Fprintf(out, "purge %s: %d keys\n", pattern, n)
+		return err
+	default:
+		return fmt.Errorf("redis: unknown command %q, expected list/count/purge", cmd)
+	}
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+)
+
+func TestKey(t *testing.T) {
+	t.Cleanup(func() { _ = SetNamespace("") })
+
+	if got := Key(KeyLock, "{order}"); got != "lock:{order}" {
+		t.Fatalf("未设置命名空间 Key = %q", got)
+	}
+	if err := SetNamespace("shop:"); err != nil {
+		t.Fatalf("SetNamespace: %v", err)
+	}
+	if got := Key(KeyToken, "alice", "accesstoken"); got != "shop:coreos:alice:accesstoken" {
+		t.Fatalf("Key = %q", got)
+	}
+	if got := KeyPattern(KeyCache); got != "shop:cache:*" {
+		t.Fatalf("KeyPattern = %q", got)
+	}
+	if got := KeyPattern(""); got != "shop:*" {
+		t.Fatalf("KeyPattern = %q", got)
+	}
+	if err := SetNamespace("{shop}"); err == nil {
+		t.Fatalf("命名空间包含 hash tag 应返回错误")
+	}
+}
+
+func TestRunKeys(t *testing.T) {
+	t.Cleanup(func() { _ = SetNamespace("") })
+	ctx := context.Background()
+	mr, client := testutil.NewRedis(t)
+
+	_ = mr.Set("other:cache:a", "1")
+	_ = SetNamespace("shop")
+	for _, key := range []string{Key(KeyCache, "a"), Key(KeyCache, "b"), Key(KeyCaptcha, "c")} {
+		_ = mr.Set(key, "1")
+	}
+
+	var out bytes.Buffer
+	if err := RunKeys(ctx, client, []string{"list", KeyCache}, &out); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got := strings.Fields(out.String()); len(got) != 2 {
+		t.Fatalf("list = %v", got)
+	}
+
+	keys, _ := ListKeys(ctx, client, KeyPattern(""), 1)
+	if len(keys) != 1 {
+		t.Fatalf("limit 未生效: %v", keys)
+	}
+
+	out.Reset()
+	if err := RunKeys(ctx, client, []string{"purge", "--dry-run"}, &out); err != nil || !strings.Contains(out.String(), "3 keys") {
+		t.Fatalf("purge --dry-run: %v, %s", err, out.String())
+	}
+	if !mr.Exists(Key(KeyCache, "a")) {
+		t.Fatalf("dry-run 不应删除")
+	}
+
+	n, err := PurgeKeys(ctx, client, KeyPattern(""))
+	if err != nil || n != 3 {
+		t.Fatalf("PurgeKeys = %d, %v", n, err)
+	}
+	if !mr.Exists("other:cache:a") {
+		t.Fatalf("不应删除其他命名空间的 key")
+	}
+
+	_ = SetNamespace("")
+	if err = RunKeys(ctx, client, []string{"purge"}, &out); err == nil {
+		t.Fatalf("未设置命名空间时 purge 必须指定 component")
+	}
+}
@@ -0,0 +1,255 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLockRetryInterval    = 50 * time.Millisecond
+	defaultLockMaxRetryInterval = time.Second
+)
+
+var (
+	// ErrLockNotAcquired 等待超时仍未获取到锁
+	ErrLockNotAcquired = errors.New("redis: lock not acquired")
+	// ErrLockNotHeld 锁已过期或被其他持有者获取
+	ErrLockNotHeld = errors.New("redis: lock not held")
+
+	// acquireScript 加锁成功时递增并返回栅栏令牌, 失败返回0
+	acquireScript = goRedis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`)
+
+	// releaseScript 只删除自己持有的锁
+	releaseScript = goRedis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// refreshScript 只续期自己持有的锁
+	refreshScript = goRedis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// LockOptions 分布式锁配置
+type LockOptions struct {
+	Wait             time.Duration // Wait 获取锁最长等待时间, 0 只尝试一次; ctx 取消时同样停止等待
+	RetryInterval    time.Duration // RetryInterval 重试间隔, 指数退避并加随机抖动, 默认50ms
+	MaxRetryInterval time.Duration // MaxRetryInterval 重试间隔上限, 默认1s
+	DisableRenew     bool          // DisableRenew 关闭自动续期, 持有时间超过 ttl 后锁自动失效
+}
+
+// Locker 基于redis的分布式锁, 适用于单节点/哨兵/集群客户端
+type Locker struct {
+	client goRedis.UniversalClient
+	opts   LockOptions
+}
+
+// Lock 已获取的锁, 持有期间按 ttl/3 自动续期, 使用完必须调用 Release
+type Lock struct {
+	client goRedis.UniversalClient
+	key    string
+	value  string
+	token  int64
+	ttl    time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	lost     chan struct{}
+}
+
+// NewLocker 创建分布式锁, opts 为 nil 时使用默认配置
+func NewLocker(client goRedis.UniversalClient, opts *LockOptions) *Locker {
+	l := &Locker{client: client}
+	if opts != nil {
+		l.opts = *opts
+	}
+	if l.opts.RetryInterval <= 0 {
+		l.opts.RetryInterval = defaultLockRetryInterval
+	}
+	if l.opts.MaxRetryInterval <= 0 {
+		l.opts.MaxRetryInterval = defaultLockMaxRetryInterval
+	}
+	if l.opts.MaxRetryInterval < l.opts.RetryInterval {
+		l.opts.MaxRetryInterval = l.opts.RetryInterval
+	}
+	return l
+}
+
+// AcquireLock 使用 Redisclient 获取锁, wait 为最长等待时间, 0 只尝试一次
+func AcquireLock(ctx context.Context, key string, ttl, wait time.Duration) (*Lock, error) {
+	return NewLocker(Redisclient, &LockOptions{Wait: wait}).Lock(ctx, key, ttl)
+}
+
+// Lock 获取锁, 在 Wait 时间内按退避策略重试, 超时返回 ErrLockNotAcquired
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	value, err := randomValue()
+	if err != nil {
+		return nil, err
+	}
+
+	var deadline <-chan time.Time
+	if l.opts.Wait > 0 {
+		timer := time.NewTimer(l.opts.Wait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	lockKey, fencingKey := lockKeys(key)
+	interval := l.opts.RetryInterval
+	for {
+		token, err := acquireScript.Run(ctx, l.client, []string{lockKey, fencingKey}, value, ttl.Milliseconds()).Int64()
+		if err != nil {
+			return nil, err
+		}
+		if token > 0 {
+			lock := &Lock{
+				client: l.client,
+				key:    lockKey,
+				value:  value,
+				token:  token,
+				ttl:    ttl,
+				stop:   make(chan struct{}),
+				done:   make(chan struct{}),
+				lost:   make(chan struct{}),
+			}
+			if l.opts.DisableRenew {
+				close(lock.done)
+			} else {
+				go lock.renew()
+			}
+			return lock, nil
+		}
+
+		if deadline == nil {
+			return nil, ErrLockNotAcquired
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(ErrLockNotAcquired, ctx.Err())
+		case <-deadline:
+			return nil, ErrLockNotAcquired
+		case <-time.After(jitter(interval)):
+		}
+		if interval *= 2; interval > l.opts.MaxRetryInterval {
+			interval = l.opts.MaxRetryInterval
+		}
+	}
+}
+
+// Key 锁在redis中的key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 栅栏令牌, 同一个 key 每次加锁单调递增; 写入下游存储时带上令牌并拒绝更小的令牌,
+// 可避免锁过期后旧持有者的延迟写入覆盖新持有者的数据
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Lost 锁丢失(续期失败或被他人获取)时关闭, 持有者应停止受保护的操作
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 手动续期为 ttl, 锁已丢失时返回 ErrLockNotHeld
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ok, err := refreshScript.Run(ctx, l.client, []string{l.key}, l.value, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 停止续期并释放锁, 只会删除自己持有的锁; 锁已过期或被他人获取时返回 ErrLockNotHeld
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	ok, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.value).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// renew 每 ttl/3 续期一次; 锁被他人获取或超过 ttl 未能续期成功时视为丢失
+func (l *Lock) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		err := l.Refresh(ctx, l.ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			renewed = time.Now()
+			continue
+		case errors.Is(err, ErrLockNotHeld) || time.Since(renewed) >= l.ttl:
+			logger.Logger.Warn("Redis", zap.String("lock", l.key), zap.Int64("token", l.token), zap.String("msg", "锁已丢失"), zap.Error(err))
+			close(l.lost)
+			return
+		default:
+			logger.Logger.Warn("Redis", zap.String("lock", l.key), zap.String("msg", "锁续期失败, 重试中"), zap.Error(err))
+		}
+	}
+}
+
+// lockKeys 锁 key 和栅栏令牌 key, 使用 hash tag 保证集群模式下位于同一 slot
+func lockKeys(key string) (string, string) {
+	lockKey := Key(KeyLock, "{"+key+"}")
+	return lockKey, lockKey + ":fencing"
+}
+
+// randomValue 锁持有者标识
+func randomValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// jitter 在 [d, 2d) 之间随机
+func jitter(d time.Duration) time.Duration {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(d)))
+	if err != nil {
+		return d
+	}
+	return d + time.Duration(n.Int64())
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+func TestLockFencingAndRelease(t *testing.T) {
+	ctx := context.Background()
+	_, client := testutil.NewRedis(t)
+	locker := NewLocker(client, &LockOptions{DisableRenew: true})
+
+	first, err := locker.Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, err = locker.Lock(ctx, "job", time.Second); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("重复加锁应失败, got %v", err)
+	}
+
+	if err = first.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err = first.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("重复释放应返回 ErrLockNotHeld, got %v", err)
+	}
+
+	second, err := locker.Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	defer second.Release(ctx)
+	if second.Token() <= first.Token() {
+		t.Fatalf("栅栏令牌应单调递增: %d <= %d", second.Token(), first.Token())
+	}
+}
+
+func TestLockReleaseOnlyOwn(t *testing.T) {
+	ctx := context.Background()
+	mr, client := testutil.NewRedis(t)
+	locker := NewLocker(client, &LockOptions{DisableRenew: true})
+
+	first, err := locker.Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	mr.FastForward(2 * time.Second)
+
+	second, err := locker.Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("过期后应能加锁: %v", err)
+	}
+	if err = first.Release(ctx); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("不应释放他人的锁, got %v", err)
+	}
+	if !mr.Exists(second.Key()) {
+		t.Fatalf("他人的锁被删除")
+	}
+}
+
+func TestLockWaitAndRenew(t *testing.T) {
+	ctx := context.Background()
+	mr, client := testutil.NewRedis(t)
+
+	held, err := NewLocker(client, nil).Lock(ctx, "job", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	// 自动续期后 ttl 被刷新
+	time.Sleep(250 * time.Millisecond)
+	if ttl := mr.TTL(held.Key()); ttl < 200*time.Millisecond {
+		t.Fatalf("未自动续期, ttl = %v", ttl)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = held.Release(ctx)
+	}()
+	waiter, err := NewLocker(client, &LockOptions{Wait: 2 * time.Second}).Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("等待加锁失败: %v", err)
+	}
+	defer waiter.Release(ctx)
+
+	// 锁被删除后续期检测到丢失
+	mr.Del(waiter.Key())
+	select {
+	case <-waiter.Lost():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("未检测到锁丢失")
+	}
+}
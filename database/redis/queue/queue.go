@@ -0,0 +1,473 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis"
+	"github.com/bigbigliu/go-core/logger"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultName              = "default"
+	defaultConcurrency       = 10
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxRetry          = 3
+	defaultRetryBackoff      = time.Second
+	defaultMaxRetryBackoff   = 10 * time.Minute
+	defaultPollInterval      = time.Second
+)
+
+var (
+	// ErrNoHandler 任务类型未注册处理函数
+	ErrNoHandler = errors.New("queue: no handler registered")
+	// ErrStarted 队列已启动
+	ErrStarted = errors.New("queue: already started")
+	// ErrJobNotFound 死信任务不存在
+	ErrJobNotFound = errors.New("queue: job not found")
+)
+
+// Handler 任务处理函数, 返回错误时按指数退避重试, 超过最大重试次数后进入死信列表;
+// ctx 携带入队时的 request ID, 队列关闭超时时取消
+type Handler func(ctx context.Context, job *Job) error
+
+// Job 任务
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	MaxRetry   int             `json:"max_retry"`
+	RequestID  string          `json:"request_id,omitempty"` // RequestID 入队请求的 request ID, 用于串联日志
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	LastError  string          `json:"last_error,omitempty"` // LastError 最近一次执行失败的原因
+	Attempt    int             `json:"-"`                    // Attempt 当前为第几次执行, 从1开始
+
+	deadline int64 // deadline 当前 worker 设置的可见性超时时间(ms), 用于确认任务仍归当前 worker 所有
+}
+
+// Bind 将任务参数反序列化到 v
+func (j *Job) Bind(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Options 队列配置
+type Options struct {
+	Client            goRedis.UniversalClient // Client redis客户端, 为空时使用 redis.Redisclient
+	Name              string                  // Name 队列名, 默认 default
+	Concurrency       int                     // Concurrency 并发执行的 worker 数量, 默认10
+	VisibilityTimeout time.Duration           // VisibilityTimeout 可见性超时, worker 失联超过该时间后任务重新投递, 默认30s
+	MaxRetry          int                     // MaxRetry 默认最大重试次数, 默认3
+	RetryBackoff      time.Duration           // RetryBackoff 首次重试间隔, 之后指数递增, 默认1s
+	MaxRetryBackoff   time.Duration           // MaxRetryBackoff 重试间隔上限, 默认10min
+	PollInterval      time.Duration           // PollInterval 队列为空时的轮询间隔, 默认1s
+}
+
+// EnqueueOptions 入队配置
+type EnqueueOptions struct {
+	Delay    time.Duration // Delay 延迟执行时间
+	MaxRetry int           // MaxRetry 最大重试次数, 0 使用队列默认值, 小于0 不重试
+}
+
+// Queue 基于redis的任务队列, 支持延迟任务、可见性超时、指数退避重试和死信列表
+type Queue struct {
+	opts     Options
+	keys     keys
+	handlers map[string]Handler
+
+	mu      sync.Mutex
+	started bool
+	stop    chan struct{}
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// keys 队列使用的 redis key, 使用 hash tag 保证集群模式下位于同一 slot
+type keys struct {
+	jobs, ready, delayed, active, attempts, dead string
+}
+
+// New 创建队列, opts 为 nil 时使用默认配置
+func New(opts *Options) *Queue {
+	q := &Queue{handlers: make(map[string]Handler)}
+	if opts != nil {
+		q.opts = *opts
+	}
+	if q.opts.Name == "" {
+		q.opts.Name = defaultName
+	}
+	if q.opts.Concurrency <= 0 {
+		q.opts.Concurrency = defaultConcurrency
+	}
+	if q.opts.VisibilityTimeout <= 0 {
+		q.opts.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if q.opts.MaxRetry == 0 {
+		q.opts.MaxRetry = defaultMaxRetry
+	}
+	if q.opts.RetryBackoff <= 0 {
+		q.opts.RetryBackoff = defaultRetryBackoff
+	}
+	if q.opts.MaxRetryBackoff <= 0 {
+		q.opts.MaxRetryBackoff = defaultMaxRetryBackoff
+	}
+	if q.opts.PollInterval <= 0 {
+		q.opts.PollInterval = defaultPollInterval
+	}
+
+	prefix := redis.Key(redis.KeyQueue, "{"+q.opts.Name+"}") + ":"
+	q.keys = keys{
+		jobs:     prefix + "jobs",
+		ready:    prefix + "ready",
+		delayed:  prefix + "delayed",
+		active:   prefix + "active",
+		attempts: prefix + "attempts",
+		dead:     prefix + "dead",
+	}
+	return q
+}
+
+// Handle 注册任务处理函数, 需在 Start 之前调用
+func (q *Queue) Handle(jobType string, h Handler) {
+	q.handlers[jobType] = h
+}
+
+// Enqueue 入队, payload 序列化为 json; ctx 中的 request ID 随任务传递给处理函数; opts 可为 nil
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload any, opts *EnqueueOptions) (string, error) {
+	if opts == nil {
+		opts = &EnqueueOptions{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("queue: marshal payload: %w", err)
+	}
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	job := &Job{
+		ID:         id,
+		Type:       jobType,
+		Payload:    data,
+		MaxRetry:   opts.MaxRetry,
+		EnqueuedAt: time.Now(),
+	}
+	if job.MaxRetry == 0 {
+		job.MaxRetry = q.opts.MaxRetry
+	}
+	job.RequestID, _ = ctx.Value(logger.RequestIDKey).(string)
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	var runAt int64
+	if opts.Delay > 0 {
+		runAt = time.Now().Add(opts.Delay).UnixMilli()
+	}
+
+	err = enqueueScript.Run(ctx, q.client(), []string{q.keys.jobs, q.keys.ready, q.keys.delayed}, id, raw, runAt).Err()
+	if err != nil {
+		return "", err
+	}
+	logger.Logger.WithOptions(logger.WithContext(ctx)).Info("Queue", zap.String("queue", q.opts.Name), zap.String("job_id", id), zap.String("type", jobType), zap.Duration("delay", opts.Delay), zap.String("msg", "任务入队"))
+	return id, nil
+}
+
+// Start 启动 worker, 非阻塞; 通过 Shutdown 停止
+func (q *Queue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return ErrStarted
+	}
+	q.started = true
+	q.stop = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	for i := 0; i < q.opts.Concurrency; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	logger.Logger.Info("Queue", zap.String("queue", q.opts.Name), zap.Int("concurrency", q.opts.Concurrency), zap.String("msg", "队列已启动"))
+	return nil
+}
+
+// Shutdown 停止拉取新任务并等待执行中的任务完成; ctx 超时后取消执行中任务的 ctx 并返回,
+// 未完成的任务在可见性超时后重新投递
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return nil
+	}
+	q.started = false
+	close(q.stop)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		logger.Logger.Info("Queue", zap.String("queue", q.opts.Name), zap.String("msg", "队列已停止"))
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		logger.Logger.Warn("Queue", zap.String("queue", q.opts.Name), zap.String("msg", "等待任务完成超时, 已取消执行中的任务"))
+		return ctx.Err()
+	}
+}
+
+// DeadJobs 死信列表中最近的 limit 个任务
+func (q *Queue) DeadJobs(ctx context.Context, limit int64) ([]*Job, error) {
+	ids, err := q.client().LRange(ctx, q.keys.dead, 0, limit-1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	values, err := q.client().HMGet(ctx, q.keys.jobs, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		job := &Job{}
+		if err = json.Unmarshal([]byte(s), job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RequeueDead 将死信任务重新投递, 执行次数重置
+func (q *Queue) RequeueDead(ctx context.Context, id string) error {
+	ok, err := requeueDeadScript.Run(ctx, q.client(), []string{q.keys.dead, q.keys.ready, q.keys.attempts}, id).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// work worker 循环
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		job, err := q.dequeue(ctx)
+		if err != nil {
+			logger.Logger.Warn("Queue", zap.String("queue", q.opts.Name), zap.String("msg", "拉取任务失败"), zap.Error(err))
+		}
+		if job == nil {
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(q.opts.PollInterval):
+			}
+			continue
+		}
+		q.process(ctx, job)
+	}
+}
+
+// dequeue 取出一个任务, 队列为空时返回 nil
+func (q *Queue) dequeue(ctx context.Context) (*Job, error) {
+	now := time.Now()
+	deadline := now.Add(q.opts.VisibilityTimeout).UnixMilli()
+	res, err := dequeueScript.Run(ctx, q.client(),
+		[]string{q.keys.jobs, q.keys.ready, q.keys.delayed, q.keys.active, q.keys.attempts},
+		now.UnixMilli(), deadline,
+	).Slice()
+	if err == goRedis.Nil || (err == nil && len(res) != 3) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := res[1].(string)
+	attempt, _ := res[2].(int64)
+	job := &Job{}
+	if err = json.Unmarshal([]byte(data), job); err != nil {
+		return nil, fmt.Errorf("queue: unmarshal job %v: %w", res[0], err)
+	}
+	job.Attempt = int(attempt)
+	job.deadline = deadline
+	return job, nil
+}
+
+// process 执行任务并根据结果确认、重试或进入死信列表
+func (q *Queue) process(ctx context.Context, job *Job) {
+	ctx = context.WithValue(ctx, logger.RequestIDKey, job.RequestID)
+	log := logger.Logger.WithOptions(logger.WithContext(ctx)).With(
+		zap.String("queue", q.opts.Name),
+		zap.String("job_id", job.ID),
+		zap.String("type", job.Type),
+		zap.Int("attempt", job.Attempt),
+	)
+
+	stopExtend := q.extendVisibility(job)
+	begin := time.Now()
+	err := q.handle(ctx, job)
+	stopExtend()
+	elapsed := time.Since(begin)
+
+	// 使用独立 ctx 保证关闭时仍能写回任务状态
+	stateCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		owned, err := q.ack(stateCtx, job)
+		switch {
+		case err != nil:
+			log.Error("Queue", zap.String("msg", "确认任务失败"), zap.Error(err))
+		case !owned:
+			log.Warn("Queue", zap.String("msg", "任务已超时并重新投递, 忽略本次确认"), zap.Duration("elapsed", elapsed))
+		default:
+			log.Info("Queue", zap.String("msg", "任务完成"), zap.Duration("elapsed", elapsed))
+		}
+		return
+	}
+
+	job.LastError = err.Error()
+	raw, _ := json.Marshal(job)
+	if errors.Is(err, ErrNoHandler) || job.MaxRetry < 0 || job.Attempt > job.MaxRetry {
+		owned, scriptErr := q.finish(stateCtx, deadScript, []string{q.keys.jobs, q.keys.active, q.keys.dead}, job, raw)
+		switch {
+		case scriptErr != nil:
+			log.Error("Queue", zap.String("msg", "任务写入死信列表失败"), zap.Error(scriptErr))
+		case !owned:
+			log.Warn("Queue", zap.String("msg", "任务已超时并重新投递, 忽略本次失败"), zap.Duration("elapsed", elapsed), zap.Error(err))
+		default:
+			log.Error("Queue", zap.String("msg", "任务失败, 已进入死信列表"), zap.Duration("elapsed", elapsed), zap.Error(err))
+		}
+		return
+	}
+
+	backoff := q.backoff(job.Attempt)
+	runAt := time.Now().Add(backoff).UnixMilli()
+	owned, scriptErr := q.finish(stateCtx, retryScript, []string{q.keys.jobs, q.keys.active, q.keys.delayed}, job, raw, runAt)
+	switch {
+	case scriptErr != nil:
+		log.Error("Queue", zap.String("msg", "任务重试失败"), zap.Error(scriptErr))
+	case !owned:
+		log.Warn("Queue", zap.String("msg", "任务已超时并重新投递, 忽略本次失败"), zap.Duration("elapsed", elapsed), zap.Error(err))
+	default:
+		log.Warn("Queue", zap.String("msg", "任务失败, 等待重试"), zap.Duration("elapsed", elapsed), zap.Duration("backoff", backoff), zap.Error(err))
+	}
+}
+
+// ack 确认任务完成, 任务已被重新投递给其他 worker 时返回 false
+func (q *Queue) ack(ctx context.Context, job *Job) (bool, error) {
+	return q.finish(ctx, ackScript, []string{q.keys.jobs, q.keys.active, q.keys.attempts}, job)
+}
+
+// finish 执行写回任务状态的脚本, 脚本校验可见性超时时间仍为当前 worker 设置的值; 返回 false 表示已失去任务所有权
+func (q *Queue) finish(ctx context.Context, script *goRedis.Script, keys []string, job *Job, args ...interface{}) (bool, error) {
+	args = append([]interface{}{job.ID, atomic.LoadInt64(&job.deadline)}, args...)
+	res, err := script.Run(ctx, q.client(), keys, args...).Int()
+	return res == 1, err
+}
+
+// handle 调用处理函数, panic 视为失败
+func (q *Queue) handle(ctx context.Context, job *Job) (err error) {
+	h, ok := q.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoHandler, job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("queue: handler panic: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// extendVisibility 执行期间每 VisibilityTimeout/3 延长一次可见性超时, 避免长任务被重复投递;
+// 任务已被重新投递给其他 worker 时停止延长
+func (q *Queue) extendVisibility(job *Job) (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(q.opts.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				current := atomic.LoadInt64(&job.deadline)
+				deadline := time.Now().Add(q.opts.VisibilityTimeout).UnixMilli()
+				ok, err := extendScript.Run(context.Background(), q.client(), []string{q.keys.active}, job.ID, current, deadline).Int()
+				if err != nil {
+					continue
+				}
+				if ok == 0 {
+					return
+				}
+				atomic.StoreInt64(&job.deadline, deadline)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// backoff 第 attempt 次失败后的重试间隔
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.opts.RetryBackoff
+	for i := 1; i < attempt && d < q.opts.MaxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > q.opts.MaxRetryBackoff {
+		d = q.opts.MaxRetryBackoff
+	}
+	return d
+}
+
+// client redis客户端
+func (q *Queue) client() goRedis.UniversalClient {
+	if q.opts.Client != nil {
+		return q.opts.Client
+	}
+	return redis.Redisclient
+}
+
+// newJobID 随机任务ID
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/logger"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// newTestQueue 基于 miniredis 的队列
+func newTestQueue(t *testing.T) (*miniredis.Miniredis, *Queue) {
+	t.Helper()
+	mr, client := testutil.NewRedis(t)
+
+	q := New(&Options{
+		Client:       client,
+		Name:         t.Name(),
+		Concurrency:  2,
+		MaxRetry:     2,
+		RetryBackoff: 10 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	})
+	return mr, q
+}
+
+type email struct {
+	To string `json:"to"`
+}
+
+func TestQueueProcess(t *testing.T) {
+	_, q := newTestQueue(t)
+
+	got := make(chan string, 1)
+	q.Handle("email", func(ctx context.Context, job *Job) error {
+		var e email
+		if err := job.Bind(&e); err != nil {
+			return err
+		}
+		requestID, _ := ctx.Value(logger.RequestIDKey).(string)
+		got <- e.To + "|" + requestID
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), logger.RequestIDKey, "req-1")
+	if _, err := q.Enqueue(ctx, "email", email{To: "a@example.com"}, &EnqueueOptions{Delay: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	begin := time.Now()
+	if err := q.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer q.Shutdown(context.Background())
+
+	select {
+	case v := <-got:
+		if v != "a@example.com|req-1" {
+			t.Fatalf("got %q", v)
+		}
+		if time.Since(begin) < 80*time.Millisecond {
+			t.Fatalf("延迟任务提前执行")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("任务未执行")
+	}
+}
+
+func TestQueueRetryAndDead(t *testing.T) {
+	_, q := newTestQueue(t)
+
+	var attempts int32
+	q.Handle("flaky", func(ctx context.Context, job *Job) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("boom")
+	})
+
+	ctx := context.Background()
+	id, err := q.Enqueue(ctx, "flaky", nil, nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err = q.Enqueue(ctx, "unknown", nil, nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	_ = q.Start()
+
+	var dead []*Job
+	for i := 0; i < 100 && len(dead) < 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+		dead, _ = q.DeadJobs(ctx, 10)
+	}
+	_ = q.Shutdown(ctx)
+
+	if len(dead) != 2 {
+		t.Fatalf("死信任务数量 = %d, want 2", len(dead))
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Fatalf("重试2次共执行3次, got %d", n)
+	}
+
+	if err = q.RequeueDead(ctx, id); err != nil {
+		t.Fatalf("RequeueDead: %v", err)
+	}
+	if err = q.RequeueDead(ctx, id); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("重复投递应返回 ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	_, q := newTestQueue(t)
+	q.opts.VisibilityTimeout = 50 * time.Millisecond
+
+	ctx := context.Background()
+	if _, err := q.Enqueue(ctx, "lost", nil, nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// 模拟 worker 取出任务后失联
+	first, err := q.dequeue(ctx)
+	if err != nil || first == nil {
+		t.Fatalf("dequeue: %v", err)
+	}
+	if job, _ := q.dequeue(ctx); job != nil {
+		t.Fatalf("可见性超时前不应重复投递")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	second, err := q.dequeue(ctx)
+	if err != nil || second == nil || second.ID != first.ID || second.Attempt != 2 {
+		t.Fatalf("可见性超时后应重新投递: %v, %+v", err, second)
+	}
+}
+
+func TestQueueGracefulShutdown(t *testing.T) {
+	_, q := newTestQueue(t)
+
+	started, finished := make(chan struct{}), int32(0)
+	q.Handle("slow", func(ctx context.Context, job *Job) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	})
+
+	ctx := context.Background()
+	_, _ = q.Enqueue(ctx, "slow", nil, nil)
+	_ = q.Start()
+	<-started
+
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatalf("Shutdown 应等待执行中的任务完成")
+	}
+}
+
+func TestQueueStaleAck(t *testing.T) {
+	_, q := newTestQueue(t)
+	q.opts.VisibilityTimeout = 50 * time.Millisecond
+
+	ctx := context.Background()
+	if _, err := q.Enqueue(ctx, "slow", nil, nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// 第一个 worker 超时, 任务重新投递给第二个 worker
+	stale, _ := q.dequeue(ctx)
+	time.Sleep(60 * time.Millisecond)
+	owner, err := q.dequeue(ctx)
+	if err != nil || owner == nil || owner.ID != stale.ID {
+		t.Fatalf("可见性超时后应重新投递: %v, %+v", err, owner)
+	}
+
+	// 超时 worker 的确认和重试不影响新的所有者
+	if owned, err := q.ack(ctx, stale); err != nil || owned {
+		t.Fatalf("超时 worker 的确认应被忽略: %v %v", owned, err)
+	}
+	if owned, err := q.finish(ctx, retryScript, []string{q.keys.jobs, q.keys.active, q.keys.delayed}, stale, `{"id":"overwritten"}`, 0); err != nil || owned {
+		t.Fatalf("超时 worker 的重试应被忽略: %v %v", owned, err)
+	}
+	data, err := q.client().HGet(ctx, q.keys.jobs, owner.ID).Result()
+	if err != nil || data == `{"id":"overwritten"}` {
+		t.Fatalf("任务数据不应被超时 worker 修改: %q %v", data, err)
+	}
+
+	if owned, err := q.ack(ctx, owner); err != nil || !owned {
+		t.Fatalf("所有者确认失败: %v %v", owned, err)
+	}
+	if n, _ := q.client().HLen(ctx, q.keys.jobs).Result(); n != 0 {
+		t.Fatalf("确认后应删除任务数据")
+	}
+}
+
+func TestQueueDueJobsKeepOrder(t *testing.T) {
+	_, q := newTestQueue(t)
+
+	ctx := context.Background()
+	delayed, _ := q.Enqueue(ctx, "job", nil, &EnqueueOptions{Delay: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	first, _ := q.Enqueue(ctx, "job", nil, nil)
+
+	// 到期的延迟任务排在已等待的任务之后
+	for _, want := range []string{first, delayed} {
+		job, err := q.dequeue(ctx)
+		if err != nil || job == nil || job.ID != want {
+			t.Fatalf("dequeue = %+v %v, want %s", job, err, want)
+		}
+	}
+}
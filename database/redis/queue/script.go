@@ -0,0 +1,96 @@
+package queue
+
+import goRedis "github.com/go-redis/redis/v8"
+
+var (
+	// enqueueScript 保存任务, 延迟任务进入 delayed, 否则进入 ready
+	// KEYS: jobs, ready, delayed; ARGV: id, data, runAt(ms), 0 立即执行
+	enqueueScript = goRedis.NewScript(`
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call("ZADD", KEYS[3], ARGV[3], ARGV[1])
+else
+	redis.call("LPUSH", KEYS[2], ARGV[1])
+end
+return 1`)
+
+	// dequeueScript 将到期的延迟任务和可见性超时的任务移回 ready, 再取出一个任务放入 active;
+	// 移回的任务从入队端 LPUSH, 排在已等待的任务之后, 不会插队
+	// KEYS: jobs, ready, delayed, active, attempts; ARGV: now(ms), visibility deadline(ms)
+	// 返回 {id, data, attempt} 或 nil
+	dequeueScript = goRedis.NewScript(`
+for _, key in ipairs({KEYS[3], KEYS[4]}) do
+	local due = redis.call("ZRANGEBYSCORE", key, "-inf", ARGV[1], "LIMIT", 0, 100)
+	for _, id in ipairs(due) do
+		redis.call("ZREM", key, id)
+		redis.call("LPUSH", KEYS[2], id)
+	end
+end
+local id = redis.call("RPOP", KEYS[2])
+if not id then
+	return nil
+end
+local data = redis.call("HGET", KEYS[1], id)
+if not data then
+	return nil
+end
+redis.call("ZADD", KEYS[4], ARGV[2], id)
+local attempt = redis.call("HINCRBY", KEYS[5], id, 1)
+return {id, data, attempt}`)
+
+	// extendScript 延长执行中任务的可见性超时, 可见性超时时间不是当前 worker 设置的值(任务已被重新投递)时返回0
+	// KEYS: active; ARGV: id, 当前 visibility deadline(ms), 新 visibility deadline(ms)
+	extendScript = goRedis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+return 1`)
+
+	// ackScript 任务成功, 删除任务数据; 任务已被重新投递给其他 worker 时返回0, 不做修改
+	// KEYS: jobs, active, attempts; ARGV: id, visibility deadline(ms)
+	ackScript = goRedis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[2], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("HDEL", KEYS[1], ARGV[1])
+redis.call("HDEL", KEYS[3], ARGV[1])
+return 1`)
+
+	// retryScript 任务失败, 按退避时间重新进入 delayed; 任务已被重新投递给其他 worker 时返回0, 不做修改
+	// KEYS: jobs, active, delayed; ARGV: id, visibility deadline(ms), data, runAt(ms)
+	retryScript = goRedis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[2], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+redis.call("ZADD", KEYS[3], ARGV[4], ARGV[1])
+return 1`)
+
+	// deadScript 超过最大重试次数, 进入死信列表, 保留任务数据便于排查和重新投递; 任务已被重新投递给其他 worker 时返回0
+	// KEYS: jobs, active, dead; ARGV: id, visibility deadline(ms), data
+	deadScript = goRedis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[2], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+redis.call("LPUSH", KEYS[3], ARGV[1])
+return 1`)
+
+	// requeueDeadScript 死信任务重新投递, 重置执行次数
+	// KEYS: dead, ready, attempts; ARGV: id
+	requeueDeadScript = goRedis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[3], ARGV[1])
+redis.call("LPUSH", KEYS[2], ARGV[1])
+return 1`)
+)
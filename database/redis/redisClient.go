@@ -2,40 +2,196 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
 	goRedis "github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
-	"os"
-	"strconv"
 )
 
-// Redisclient 全局redis客户端
-var Redisclient *goRedis.Client
+const (
+	// ModeSingle 单节点
+	ModeSingle = "single"
+	// ModeSentinel 哨兵
+	ModeSentinel = "sentinel"
+	// ModeCluster 集群
+	ModeCluster = "cluster"
+
+	defaultPingTimeout = 5 * time.Second
+)
+
+var (
+	// Redisclient 全局redis客户端, 单节点/哨兵/集群模式统一为 UniversalClient
+	Redisclient goRedis.UniversalClient
+	// CommandHook Redisclient 的命令日志钩子, 可通过 Stats 获取每个命令的耗时和失败次数
+	CommandHook *logger.RedisHook
+)
 
 // InitRedisReq 请求参数
 type InitRedisReq struct {
-	Addr string `json:"addr"` // Addr redis服务host
-	Port int    `json:"port"` // Port redis服务port
+	Mode string `json:"mode"` // Mode 部署模式 single(默认)/sentinel/cluster
+	Addr string `json:"addr"` // Addr redis服务host, single 模式使用
+	Port int    `json:"port"` // Port redis服务port, single 模式使用
 	Pwd  string `json:"pwd"`  // Pwd redis服务密码
-	Db   int    `json:"db"`   // Db redis服务数据库
+	Db   int    `json:"db"`   // Db redis服务数据库, cluster 模式不支持
+
+	Namespace string `json:"namespace"` // Namespace key 命名空间, 多个应用共用 redis 时设置, 见 Key
+
+	Addrs       []string `json:"addrs"`        // Addrs 节点地址 host:port, sentinel 模式为哨兵地址, cluster 模式为集群节点
+	MasterName  string   `json:"master_name"`  // MasterName sentinel 模式主节点名称
+	SentinelPwd string   `json:"sentinel_pwd"` // SentinelPwd 哨兵密码, 为空时不认证
+
+	TLS           bool `json:"tls"`             // TLS 是否启用TLS
+	TLSSkipVerify bool `json:"tls_skip_verify"` // TLSSkipVerify 跳过证书校验, 仅用于测试环境
+
+	PoolSize     int           `json:"pool_size"`      // PoolSize 连接池大小, 默认 10*CPU 核数
+	MinIdleConns int           `json:"min_idle_conns"` // MinIdleConns 最小空闲连接数
+	DialTimeout  time.Duration `json:"dial_timeout"`   // DialTimeout 建连超时, 默认5s
+	ReadTimeout  time.Duration `json:"read_timeout"`   // ReadTimeout 读超时, 默认3s
+	WriteTimeout time.Duration `json:"write_timeout"`  // WriteTimeout 写超时, 默认同 ReadTimeout
+	PoolTimeout  time.Duration `json:"pool_timeout"`   // PoolTimeout 获取连接超时, 默认 ReadTimeout+1s
+
+	SlowThreshold time.Duration `json:"slow_threshold"` // SlowThreshold 慢命令阈值, 默认100ms, 小于0 关闭
+	LogArgs       bool          `json:"log_args"`       // LogArgs 日志记录完整命令参数, 默认只记录命令和 key
 }
 
-// InitRedis 初始化redis连接
+// New 按 Mode 创建redis客户端并通过 ping 校验连接, 客户端注册 logger.RedisHook 记录命令日志
+func New(ctx context.Context, cfg *InitRedisReq) (goRedis.UniversalClient, error) {
+	client, _, err := cfg.connect(ctx)
+	return client, err
+}
+
+// InitRedis 初始化redis连接并写入 Redisclient, 设置 key 命名空间, 失败时退出进程; 需要自行处理错误时使用 New 和 SetNamespace
 func (h *InitRedisReq) InitRedis() {
-	logger.Logger.Info("Redis", zap.String("conn", "connetcing..."))
+	if err := SetNamespace(h.Namespace); err != nil {
+		logger.Logger.Error("Redis", zap.Error(err))
+		os.Exit(-1)
+	}
+	client, hook, err := h.connect(context.Background())
+	if err != nil {
+		logger.Logger.Error("Redis", zap.Stringer("redis_addr", h), zap.Error(err))
+		os.Exit(-1)
+	}
+	Redisclient = client
+	CommandHook = hook
+}
 
-	addr := h.Addr + ":" + strconv.Itoa(h.Port)
-	logger.Logger.Info("Redis", zap.String("redis_addr", addr))
-	Redisclient = goRedis.NewClient(&goRedis.Options{
-		Addr:     addr,
-		Password: h.Pwd,
-		DB:       h.Db,
-	})
+// connect 创建客户端, 注册命令日志钩子并 ping
+func (h *InitRedisReq) connect(ctx context.Context) (goRedis.UniversalClient, *logger.RedisHook, error) {
+	logger.Logger.Info("Redis", zap.String("conn", "connecting..."), zap.String("mode", h.mode()), zap.Stringer("redis_addr", h))
 
-	_, err := Redisclient.Ping(context.Background()).Result()
+	client, err := h.newClient()
 	if err != nil {
-		logger.Logger.Error("Redis", zap.Error(err))
-		os.Exit(-1)
+		return nil, nil, err
+	}
+	hook := logger.NewRedisHook(logger.Logger, &logger.RedisHookOptions{SlowThreshold: h.SlowThreshold, LogArgs: h.LogArgs})
+	client.AddHook(hook)
+
+	pingCtx, cancel := context.WithTimeout(ctx, defaultPingTimeout)
+	defer cancel()
+	if err = client.Ping(pingCtx).Err(); err != nil {
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("redis: connect %s: %w", h, err)
+	}
+
+	logger.Logger.Info("Redis", zap.String("conn", "Redis连接成功"), zap.String("mode", h.mode()))
+	return client, hook, nil
+}
+
+// newClient 按模式创建客户端
+func (h *InitRedisReq) newClient() (goRedis.UniversalClient, error) {
+	var tlsConfig *tls.Config
+	if h.TLS {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: h.TLSSkipVerify}
+	}
+
+	switch h.mode() {
+	case ModeSingle:
+		return goRedis.NewClient(&goRedis.Options{
+			Addr:         h.address(),
+			Password:     h.Pwd,
+			DB:           h.Db,
+			TLSConfig:    tlsConfig,
+			PoolSize:     h.PoolSize,
+			MinIdleConns: h.MinIdleConns,
+			DialTimeout:  h.DialTimeout,
+			ReadTimeout:  h.ReadTimeout,
+			WriteTimeout: h.WriteTimeout,
+			PoolTimeout:  h.PoolTimeout,
+		}), nil
+	case ModeSentinel:
+		if h.MasterName == "" || len(h.Addrs) == 0 {
+			return nil, fmt.Errorf("redis: sentinel mode requires master_name and addrs")
+		}
+		return goRedis.NewFailoverClient(&goRedis.FailoverOptions{
+			MasterName:       h.MasterName,
+			SentinelAddrs:    h.Addrs,
+			SentinelPassword: h.SentinelPwd,
+			Password:         h.Pwd,
+			DB:               h.Db,
+			TLSConfig:        tlsConfig,
+			PoolSize:         h.PoolSize,
+			MinIdleConns:     h.MinIdleConns,
+			DialTimeout:      h.DialTimeout,
+			ReadTimeout:      h.ReadTimeout,
+			WriteTimeout:     h.WriteTimeout,
+			PoolTimeout:      h.PoolTimeout,
+		}), nil
+	case ModeCluster:
+		if len(h.Addrs) == 0 {
+			return nil, fmt.Errorf("redis: cluster mode requires addrs")
+		}
+		if h.Db != 0 {
+			return nil, fmt.Errorf("redis: cluster mode does not support db %d", h.Db)
+		}
+		return goRedis.NewClusterClient(&goRedis.ClusterOptions{
+			Addrs:        h.Addrs,
+			Password:     h.Pwd,
+			TLSConfig:    tlsConfig,
+			PoolSize:     h.PoolSize,
+			MinIdleConns: h.MinIdleConns,
+			DialTimeout:  h.DialTimeout,
+			ReadTimeout:  h.ReadTimeout,
+			WriteTimeout: h.WriteTimeout,
+			PoolTimeout:  h.PoolTimeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported mode %q", h.Mode)
 	}
-	logger.Logger.Info("Redis", zap.String("conn", "Redis连接成功"))
+}
+
+// String 实现 fmt.Stringer, 只输出脱敏后的连接信息, 例如 redis://:***@host:port/db
+func (h InitRedisReq) String() string {
+	scheme := "redis"
+	if h.TLS {
+		scheme = "rediss"
+	}
+
+	switch h.mode() {
+	case ModeSentinel:
+		return fmt.Sprintf("%s+sentinel://:%s@%s/%d?master=%s", scheme, pkgs.RedactSecret(h.Pwd), strings.Join(h.Addrs, ","), h.Db, h.MasterName)
+	case ModeCluster:
+		return fmt.Sprintf("%s+cluster://:%s@%s", scheme, pkgs.RedactSecret(h.Pwd), strings.Join(h.Addrs, ","))
+	default:
+		return fmt.Sprintf("%s://:%s@%s/%d", scheme, pkgs.RedactSecret(h.Pwd), h.address(), h.Db)
+	}
+}
+
+// mode 部署模式, 默认 single
+func (h *InitRedisReq) mode() string {
+	if h.Mode == "" {
+		return ModeSingle
+	}
+	return h.Mode
+}
+
+// address host:port
+func (h *InitRedisReq) address() string {
+	return h.Addr + ":" + strconv.Itoa(h.Port)
 }
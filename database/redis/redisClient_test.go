@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitRedisReqString(t *testing.T) {
+	cases := []struct {
+		req  InitRedisReq
+		want string
+	}{
+		{InitRedisReq{Addr: "127.0.0.1", Port: 6379, Pwd: "secret", Db: 1}, "redis://:***@127.0.0.1:6379/1"},
+		{InitRedisReq{Mode: ModeSentinel, MasterName: "mymaster", Addrs: []string{"a:26379", "b:26379"}, Pwd: "secret"}, "redis+sentinel://:***@a:26379,b:26379/0?master=mymaster"},
+		{InitRedisReq{Mode: ModeCluster, Addrs: []string{"a:6379", "b:6379"}, Pwd: "secret", TLS: true}, "rediss+cluster://:***@a:6379,b:6379"},
+	}
+	for _, c := range cases {
+		if got := c.req.String(); got != c.want || strings.Contains(got, "secret") {
+			t.Errorf("String() = %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestNewClientValidation(t *testing.T) {
+	cases := []InitRedisReq{
+		{Mode: ModeSentinel, Addrs: []string{"a:26379"}},
+		{Mode: ModeCluster},
+		{Mode: ModeCluster, Addrs: []string{"a:6379"}, Db: 1},
+		{Mode: "proxy"},
+	}
+	for _, c := range cases {
+		if _, err := c.newClient(); err == nil {
+			t.Errorf("%+v 应返回配置错误", c)
+		}
+	}
+
+	for _, c := range []InitRedisReq{
+		{Addr: "127.0.0.1", Port: 6379},
+		{Mode: ModeSentinel, MasterName: "mymaster", Addrs: []string{"a:26379"}},
+		{Mode: ModeCluster, Addrs: []string{"a:6379"}},
+	} {
+		client, err := c.newClient()
+		if err != nil {
+			t.Fatalf("%s: %v", c.mode(), err)
+		}
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// ReplicaPolicyRoundRobin 轮询选择只读副本
+	ReplicaPolicyRoundRobin = "round_robin"
+	// ReplicaPolicyWeighted 按权重平滑轮询选择只读副本
+	ReplicaPolicyWeighted = "weighted"
+
+	resolverName               = "go-core:resolver"
+	forcePrimarySetting        = "go-core:resolver:primary"
+	defaultHealthCheckInterval = 10 * time.Second
+)
+
+// lockingClause 加锁读子句, 需在主库执行
+var lockingClause = regexp.MustCompile(`\bfor\s+(update|share|no\s+key\s+update|key\s+share)\b|\block\s+in\s+share\s+mode\b`)
+
+// Replica 只读副本配置, 未设置的驱动/用户名/密码/库名继承主库
+type Replica struct {
+	DSNParam
+	Weight int `json:"weight"` // Weight 权重, weighted 策略下生效, 默认1
+}
+
+// forcePrimaryKey 强制主库 context key
+type forcePrimaryKey struct{}
+
+// WithPrimary 返回强制走主库的 ctx, 用于写后立即读等需要强一致的场景
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// ForcePrimary gorm scope, 当前语句强制走主库 db.Scopes(database.ForcePrimary)
+func ForcePrimary(db *gorm.DB) *gorm.DB {
+	return db.Set(forcePrimarySetting, true)
+}
+
+// replicaConn 只读副本连接
+type replicaConn struct {
+	dsn     string // dsn 脱敏后的连接信息, 仅用于日志
+	db      *sql.DB
+	weight  int
+	current int // current 平滑加权轮询的当前权重
+	healthy atomic.Bool
+}
+
+// resolver 读写分离插件: 读请求路由到健康的只读副本, 写请求、事务和加锁查询走主库
+type resolver struct {
+	primary  gorm.ConnPool // primary 主库连接池, 链式复用的语句在写操作前切回
+	replicas []*replicaConn
+	policy   string
+	interval time.Duration
+	counter  atomic.Uint64
+	mu       sync.Mutex // mu 保护平滑加权轮询状态
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newResolver 打开所有只读副本连接, 不可达的副本以剔除状态启动而不是让初始化失败
+func newResolver(ctx context.Context, c *Config) (*resolver, error) {
+	r := &resolver{
+		policy:   c.ReplicaPolicy,
+		interval: c.HealthCheckInterval,
+		stop:     make(chan struct{}),
+	}
+	if r.interval <= 0 {
+		r.interval = defaultHealthCheckInterval
+	}
+
+	for _, replica := range c.Replicas {
+		param := replica.inherit(&c.DSNParam)
+		sqlDB, err := sql.Open(param.sqlDriverName(), param.DSN())
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+		sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+
+		weight := replica.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		rc := &replicaConn{dsn: param.RedactedDSN(), db: sqlDB, weight: weight}
+		rc.healthy.Store(true)
+		r.replicas = append(r.replicas, rc)
+		r.check(ctx, rc)
+	}
+	return r, nil
+}
+
+// Name 实现 gorm.Plugin
+func (r *resolver) Name() string {
+	return resolverName
+}
+
+// Initialize 实现 gorm.Plugin, 注册路由回调并启动副本健康检查. 链式复用时语句共享 ConnPool,
+// 例如 q := db.Where(...); q.Find(&x); q.Updates(...), 因此写操作前需切回主库
+func (r *resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	if err := db.Callback().Query().Before("*").Register(resolverName, r.switchReplica); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("*").Register(resolverName, r.switchReplica); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().Before("*").Register(resolverName, r.switchPrimary); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("*").Register(resolverName, r.switchPrimary); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("*").Register(resolverName, r.switchPrimary); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("*").Register(resolverName, r.switchPrimary); err != nil {
+		return err
+	}
+	go r.healthLoop()
+	return nil
+}
+
+// Close 停止健康检查并关闭所有副本连接
+func (r *resolver) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	var firstErr error
+	for _, rc := range r.replicas {
+		if err := rc.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// switchPrimary 语句此前被切换到只读副本时切回主库, 事务和固定连接保持不变
+func (r *resolver) switchPrimary(db *gorm.DB) {
+	if sqlDB, ok := db.Statement.ConnPool.(*sql.DB); ok {
+		for _, rc := range r.replicas {
+			if rc.db == sqlDB {
+				db.Statement.ConnPool = r.primary
+				return
+			}
+		}
+	}
+}
+
+// switchReplica 读请求切换到只读副本, 无可用副本时保持主库
+func (r *resolver) switchReplica(db *gorm.DB) {
+	r.switchPrimary(db)
+
+	stmt := db.Statement
+	if _, ok := stmt.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+	// db.Connection 固定的主库连接, 例如迁移锁所在的会话
+	if _, ok := stmt.ConnPool.(*sql.Conn); ok {
+		return
+	}
+	if _, ok := stmt.Settings.Load(forcePrimarySetting); ok {
+		return
+	}
+	if _, locking := stmt.Clauses["FOR"]; locking {
+		return
+	}
+	if stmt.Context != nil {
+		if forced, _ := stmt.Context.Value(forcePrimaryKey{}).(bool); forced {
+			return
+		}
+	}
+	if rawSQL := strings.TrimSpace(stmt.SQL.String()); rawSQL != "" && !isReadOnlySQL(rawSQL) {
+		return
+	}
+
+	if rc := r.pick(); rc != nil {
+		stmt.ConnPool = rc.db
+	}
+}
+
+// pick 按策略从健康副本中选择一个, 全部被剔除时返回 nil
+func (r *resolver) pick() *replicaConn {
+	healthy := make([]*replicaConn, 0, len(r.replicas))
+	for _, rc := range r.replicas {
+		if rc.healthy.Load() {
+			healthy = append(healthy, rc)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if r.policy == ReplicaPolicyWeighted {
+		return r.pickWeighted(healthy)
+	}
+	return healthy[(r.counter.Add(1)-1)%uint64(len(healthy))]
+}
+
+// pickWeighted 平滑加权轮询
+func (r *resolver) pickWeighted(healthy []*replicaConn) *replicaConn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		best  *replicaConn
+		total int
+	)
+	for _, rc := range healthy {
+		rc.current += rc.weight
+		total += rc.weight
+		if best == nil || rc.current > best.current {
+			best = rc
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// healthLoop 定期探测副本, 失败剔除, 恢复后重新加入
+func (r *resolver) healthLoop() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			for _, rc := range r.replicas {
+				r.check(context.Background(), rc)
+			}
+		}
+	}
+}
+
+// check 探测单个副本并在状态变化时记录日志
+func (r *resolver) check(ctx context.Context, rc *replicaConn) {
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	err := rc.db.PingContext(ctx)
+	healthy := err == nil
+	if rc.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		logger.Logger.Info("DB", zap.String("replica", rc.dsn), zap.String("conn", "只读副本恢复"))
+	} else {
+		logger.Logger.Warn("DB", zap.String("replica", rc.dsn), zap.String("conn", "只读副本剔除"), zap.Error(err))
+	}
+}
+
+// isReadOnlySQL 判断原生SQL是否可以路由到只读副本: 以 select 开头且 FROM 之后没有加锁子句
+// (FOR UPDATE [NOWAIT|SKIP LOCKED]、FOR SHARE、FOR NO KEY UPDATE、FOR KEY SHARE、LOCK IN SHARE MODE)
+func isReadOnlySQL(rawSQL string) bool {
+	rawSQL = strings.ToLower(strings.TrimRight(strings.TrimSpace(rawSQL), "; \t\r\n"))
+	if !strings.HasPrefix(rawSQL, "select") {
+		return false
+	}
+	from := strings.Index(rawSQL, "from")
+	if from < 0 {
+		return true
+	}
+	return !lockingClause.MatchString(rawSQL[from:])
+}
+
+// inherit 补全副本未设置的连接参数
+func (h *Replica) inherit(primary *DSNParam) *DSNParam {
+	param := h.DSNParam
+	if param.Driver == "" {
+		param.Driver = primary.driver()
+	}
+	if param.DbPort == 0 {
+		param.DbPort = primary.DbPort
+	}
+	if param.DbUser == "" {
+		param.DbUser = primary.DbUser
+		param.DbPwd = primary.DbPwd
+	}
+	if param.DbName == "" {
+		param.DbName = primary.DbName
+	}
+	if param.SSLMode == "" {
+		param.SSLMode = primary.SSLMode
+	}
+	if param.Params == "" {
+		param.Params = primary.Params
+	}
+	return &param
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+func newTestResolver(policy string, weights ...int) *resolver {
+	r := &resolver{policy: policy}
+	for _, w := range weights {
+		rc := &replicaConn{weight: w}
+		rc.healthy.Store(true)
+		r.replicas = append(r.replicas, rc)
+	}
+	return r
+}
+
+func TestResolverPickWeighted(t *testing.T) {
+	r := newTestResolver(ReplicaPolicyWeighted, 3, 1)
+
+	counts := map[*replicaConn]int{}
+	for i := 0; i < 8; i++ {
+		counts[r.pick()]++
+	}
+	if counts[r.replicas[0]] != 6 || counts[r.replicas[1]] != 2 {
+		t.Errorf("权重分配错误: %d/%d", counts[r.replicas[0]], counts[r.replicas[1]])
+	}
+}
+
+func TestResolverPickSkipsEjected(t *testing.T) {
+	r := newTestResolver(ReplicaPolicyRoundRobin, 1, 1)
+	r.replicas[0].healthy.Store(false)
+
+	for i := 0; i < 4; i++ {
+		if rc := r.pick(); rc != r.replicas[1] {
+			t.Fatalf("剔除的副本不应被选中")
+		}
+	}
+
+	// 所有副本都被剔除时回退主库
+	r.replicas[1].healthy.Store(false)
+	if rc := r.pick(); rc != nil {
+		t.Errorf("无健康副本时应返回 nil")
+	}
+}
+
+func TestIsReadOnlySQL(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT * FROM users": true,
+		"  select 1;  ":       true,
+		"select id from users where name = 'before'":            true,
+		"select id from users for update":                       false,
+		"select id from users FOR UPDATE;":                      false,
+		"select id from users for update \n":                    false,
+		"select id from users for update nowait":                false,
+		"select id from users for update skip locked":           false,
+		"SELECT id FROM users FOR SHARE":                        false,
+		"select id from users for no key update":                false,
+		"select id from users lock in share mode":               false,
+		"select * from users u join orders o on 1=1 for update": false,
+		"UPDATE users SET name = 'a'":                           false,
+		"insert into users (name) values(1)":                    false,
+	}
+	for sql, want := range cases {
+		if got := isReadOnlySQL(sql); got != want {
+			t.Errorf("isReadOnlySQL(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+type routedItem struct {
+	ID   uint
+	Name string
+}
+
+func TestResolverWriteAfterReadOnSameChain(t *testing.T) {
+	cfg := newTestDB(t)
+	replicaDSN := "file:" + t.Name() + "_replica?mode=memory&cache=shared"
+	cfg.Replicas = []Replica{{DSNParam: DSNParam{DbName: replicaDSN}}}
+	db, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = closeDB(db) })
+
+	// 主库和副本各自建表, 用 name 区分读到的是哪个库
+	replica, err := gorm.Open(sqlite.Open(replicaDSN), &gorm.Config{Logger: gormLog.Discard})
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	if sqlDB, err := replica.DB(); err == nil {
+		t.Cleanup(func() { _ = sqlDB.Close() })
+	}
+	for name, conn := range map[string]*gorm.DB{"primary": db, "replica": replica} {
+		if err = conn.AutoMigrate(&routedItem{}); err != nil {
+			t.Fatalf("AutoMigrate: %v", err)
+		}
+		conn.Session(&gorm.Session{SkipHooks: true}).Create(&routedItem{ID: 1, Name: name})
+	}
+
+	q := db.Model(&routedItem{}).Where("id = ?", 1)
+	read := func() string {
+		t.Helper()
+		var item routedItem
+		if err := q.Find(&item).Error; err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		return item.Name
+	}
+	names := func() (string, string) {
+		var onPrimary, onReplica routedItem
+		db.Scopes(ForcePrimary).Find(&onPrimary, 1)
+		replica.Find(&onReplica, 1)
+		return onPrimary.Name, onReplica.Name
+	}
+
+	if name := read(); name != "replica" {
+		t.Fatalf("读请求应路由到副本, got %q", name)
+	}
+	if err = q.Exec("UPDATE routed_items SET name = ? WHERE id = 1", "updated").Error; err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if p, r := names(); p != "updated" || r != "replica" {
+		t.Fatalf("Exec 应切回主库: primary=%q replica=%q", p, r)
+	}
+
+	read()
+	if err = q.Delete(&routedItem{}).Error; err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if p, r := names(); p != "" || r != "replica" {
+		t.Fatalf("Delete 应切回主库: primary=%q replica=%q", p, r)
+	}
+}
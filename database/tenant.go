@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// FieldTenantID 租户字段, 模型包含该字段即启用租户隔离
+	FieldTenantID = "TenantID"
+
+	tenantCallbackName = "go-core:tenant"
+	skipTenantSetting  = "go-core:tenant:skip"
+)
+
+var (
+	// ErrMissingTenant 租户隔离模型的语句缺少租户, 未显式跳过时拒绝执行
+	ErrMissingTenant = errors.New("database: tenant not found in context")
+	// ErrTenantMismatch 新增记录的租户与当前租户不一致
+	ErrTenantMismatch = errors.New("database: tenant mismatch")
+)
+
+// skipTenantKey 跳过租户隔离 context key
+type skipTenantKey struct{}
+
+// WithTenant 设置当前租户, 优先于 gin.Context 中由 TenantMiddleware 设置的 tenant_id
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return pkgs.WithTenant(ctx, tenantID)
+}
+
+// WithoutTenant 返回跳过租户隔离的 ctx, 仅用于管理后台、跨租户统计等场景
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+// SkipTenant gorm scope, 当前语句跳过租户隔离 db.Scopes(database.SkipTenant)
+func SkipTenant(db *gorm.DB) *gorm.DB {
+	return db.Set(skipTenantSetting, true)
+}
+
+// RegisterTenantCallbacks 注册租户隔离回调, database.New 默认已注册; 对包含 TenantID 字段的模型:
+// 查询/更新/删除追加 tenant_id = ? 条件, 新增时填充 TenantID, 更新时禁止修改 TenantID;
+// ctx 中没有租户且未通过 WithoutTenant/SkipTenant 显式跳过时返回 ErrMissingTenant.
+// Raw/Exec 原生SQL和 Joins 关联表不做处理, 需要自行带上租户条件
+func RegisterTenantCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register(tenantCallbackName, tenantCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("*").Register(tenantCallbackName, tenantScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("*").Register(tenantCallbackName, tenantScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("*").Register(tenantCallbackName, tenantUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("*").Register(tenantCallbackName, tenantScope)
+}
+
+// tenantOf 当前语句的租户; 模型不需要隔离或显式跳过时 ok 为 false, 缺少租户时写入 ErrMissingTenant
+func tenantOf(db *gorm.DB) (field *schema.Field, tenantID string, ok bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return nil, "", false
+	}
+	if field = stmt.Schema.LookUpField(FieldTenantID); field == nil {
+		return nil, "", false
+	}
+	if _, skip := stmt.Settings.Load(skipTenantSetting); skip {
+		return nil, "", false
+	}
+	if skip, _ := stmt.Context.Value(skipTenantKey{}).(bool); skip {
+		return nil, "", false
+	}
+
+	if tenantID, ok = pkgs.TenantFromContext(stmt.Context); !ok {
+		_ = db.AddError(ErrMissingTenant)
+	}
+	return field, tenantID, ok
+}
+
+// tenantScope 查询/删除追加租户条件
+func tenantScope(db *gorm.DB) {
+	field, tenantID, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// tenantUpdate 更新追加租户条件, 并将 TenantID 固定为当前租户, 防止记录被移动到其他租户
+func tenantUpdate(db *gorm.DB) {
+	field, tenantID, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	tenantScope(db)
+
+	if dest, isMap := updateMap(db); isMap {
+		delete(dest, field.Name)
+		if _, set := dest[field.DBName]; !set {
+			return
+		}
+	}
+	db.Statement.SetColumn(field.DBName, tenantID, true)
+}
+
+// tenantCreate 新增时填充租户, 已显式赋值且与当前租户不一致时返回 ErrTenantMismatch
+func tenantCreate(db *gorm.DB) {
+	field, tenantID, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		setMapTenant(db, dest, field, tenantID)
+	case []map[string]interface{}:
+		for _, m := range dest {
+			setMapTenant(db, m, field, tenantID)
+		}
+	default:
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				setFieldTenant(db, field, stmt.ReflectValue.Index(i), tenantID)
+			}
+		case reflect.Struct:
+			setFieldTenant(db, field, stmt.ReflectValue, tenantID)
+		}
+	}
+}
+
+// setFieldTenant 结构体租户为空时赋值, 不一致时报错
+func setFieldTenant(db *gorm.DB, field *schema.Field, rv reflect.Value, tenantID string) {
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if v, zero := field.ValueOf(db.Statement.Context, rv); !zero && v != tenantID {
+		_ = db.AddError(ErrTenantMismatch)
+		return
+	}
+	setFieldIfZero(db, field, rv, tenantID)
+}
+
+// setMapTenant map 未指定租户时赋值, 不一致时报错
+func setMapTenant(db *gorm.DB, m map[string]interface{}, field *schema.Field, tenantID string) {
+	for _, key := range []string{field.Name, field.DBName} {
+		if v, ok := m[key]; ok && v != tenantID {
+			_ = db.AddError(ErrTenantMismatch)
+			return
+		}
+	}
+	setMapIfAbsent(m, field, tenantID)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type tenantDoc struct {
+	gorm.Model
+	TenantID string `gorm:"size:64;index"`
+	Title    string
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err = db.AutoMigrate(&tenantDoc{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	acme, globex := WithTenant(context.Background(), "acme"), WithTenant(context.Background(), "globex")
+	docs := []tenantDoc{{Title: "a1"}, {Title: "a2"}}
+	if err = db.WithContext(acme).Create(&docs).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if docs[0].TenantID != "acme" {
+		t.Fatalf("新增未填充租户: %+v", docs[0])
+	}
+	if err = db.WithContext(globex).Create(&tenantDoc{Title: "g1"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 查询与计数只返回当前租户
+	var list []tenantDoc
+	db.WithContext(globex).Find(&list)
+	var count int64
+	db.WithContext(globex).Model(&tenantDoc{}).Count(&count)
+	if len(list) != 1 || list[0].Title != "g1" || count != 1 {
+		t.Fatalf("查询泄露其他租户数据: %+v, count %d", list, count)
+	}
+
+	// 按主键访问其他租户的记录
+	if err = db.WithContext(globex).First(&tenantDoc{}, docs[0].ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("不应读取到其他租户的记录, got %v", err)
+	}
+	tx := db.WithContext(globex).Model(&tenantDoc{}).Where("id = ?", docs[0].ID).Update("title", "hacked")
+	if tx.Error != nil || tx.RowsAffected != 0 {
+		t.Fatalf("不应更新其他租户的记录: %v, %d", tx.Error, tx.RowsAffected)
+	}
+	tx = db.WithContext(globex).Delete(&tenantDoc{}, docs[1].ID)
+	if tx.Error != nil || tx.RowsAffected != 0 {
+		t.Fatalf("不应删除其他租户的记录: %v, %d", tx.Error, tx.RowsAffected)
+	}
+
+	// 不允许修改租户或为其他租户新增
+	values := map[string]interface{}{"TenantID": "globex", "tenant_id": "globex", "title": "a1"}
+	if err = db.WithContext(acme).Model(&docs[0]).Updates(values).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	if len(values) != 3 || values["TenantID"] != "globex" || values["tenant_id"] != "globex" {
+		t.Fatalf("调用方的 map 被修改: %+v", values)
+	}
+	if err = db.WithContext(acme).Create(&tenantDoc{TenantID: "globex", Title: "x"}).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("应返回 ErrTenantMismatch, got %v", err)
+	}
+
+	// 缺少租户时拒绝执行
+	if err = db.WithContext(context.Background()).Find(&list).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("应返回 ErrMissingTenant, got %v", err)
+	}
+	if err = db.Create(&tenantDoc{Title: "y"}).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("应返回 ErrMissingTenant, got %v", err)
+	}
+
+	// 显式跳过
+	db.WithContext(WithoutTenant(context.Background())).Model(&tenantDoc{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("WithoutTenant count = %d, want 3", count)
+	}
+	db.Scopes(SkipTenant).Model(&tenantDoc{}).Where("tenant_id = ?", "acme").Count(&count)
+	if count != 2 {
+		t.Fatalf("SkipTenant acme count = %d, want 2 (租户不应被修改)", count)
+	}
+}
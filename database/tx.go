@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// mysqlDeadlock mysql 死锁错误码 ER_LOCK_DEADLOCK
+	mysqlDeadlock = 1213
+	// pgDeadlock postgres 死锁错误码 deadlock_detected
+	pgDeadlock = "40P01"
+
+	defaultTxRetries      = 3
+	defaultTxRetryBackoff = 50 * time.Millisecond
+)
+
+var (
+	savepointSeq uint64
+)
+
+// txKey 事务 context key, 按连接池区分, 不同实例的事务互不干扰
+type txKey struct {
+	pool gorm.ConnPool
+}
+
+// FromContext 返回 ctx 中与 db 同一实例的事务, 不在事务中时返回 db.WithContext(ctx);
+// 仓储等数据访问代码统一通过它获取 *gorm.DB 即可自动加入外层事务
+func FromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{pool: db.Config.ConnPool}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// WithTx 在事务中执行 fn, 事务通过 ctx 传递给 fn 内的数据访问代码;
+// 嵌套调用使用保存点, 内层出错只回滚到保存点; 最外层遇到死锁时整体退避重试, fn 需要可重入
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	key := txKey{pool: db.Config.ConnPool}
+	if tx, ok := ctx.Value(key).(*gorm.DB); ok {
+		return withSavepoint(ctx, tx, fn)
+	}
+
+	log := logger.Logger.WithOptions(logger.WithContext(ctx))
+	backoff := defaultTxRetryBackoff
+	for attempt := 1; ; attempt++ {
+		begin := time.Now()
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, key, tx))
+		}, opts...)
+		if err == nil {
+			log.Info("DB", zap.String("tx", "commit"), zap.Int("attempt", attempt), zap.Duration("elapsed", time.Since(begin)))
+			return nil
+		}
+
+		if !IsDeadlock(err) || attempt > defaultTxRetries {
+			log.Warn("DB", zap.String("tx", "rollback"), zap.Int("attempt", attempt), zap.Duration("elapsed", time.Since(begin)), zap.Error(err))
+			return err
+		}
+
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		log.Warn("DB", zap.String("tx", "deadlock, retrying..."), zap.Int("attempt", attempt), zap.Duration("wait", wait), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+// withSavepoint 嵌套事务, fn 返回错误或 panic 时回滚到保存点
+func withSavepoint(ctx context.Context, tx *gorm.DB, fn func(ctx context.Context) error) (err error) {
+	name := fmt.Sprintf("sp_%d", atomic.AddUint64(&savepointSeq, 1))
+	if err = tx.WithContext(ctx).SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			if rbErr := tx.WithContext(ctx).RollbackTo(name).Error; rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+			logger.Logger.WithOptions(logger.WithContext(ctx)).Warn("DB", zap.String("tx", "rollback to savepoint"), zap.String("savepoint", name), zap.Error(err))
+		}
+	}()
+
+	err = fn(ctx)
+	panicked = false
+	return err
+}
+
+// IsDeadlock 是否为可重试的死锁错误 (mysql 1213 / postgres 40P01)
+func IsDeadlock(err error) bool {
+	var myErr *mysqlDriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == mysqlDeadlock
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgDeadlock
+	}
+	return false
+}
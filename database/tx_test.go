@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type txAccount struct {
+	ID      uint
+	Name    string
+	Balance int
+}
+
+func TestWithTx(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err = db.AutoMigrate(&txAccount{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	ctx := context.Background()
+	create := func(ctx context.Context, name string) error {
+		return FromContext(ctx, db).Create(&txAccount{Name: name}).Error
+	}
+	count := func() (n int64) {
+		db.Model(&txAccount{}).Count(&n)
+		return
+	}
+
+	// 内层失败只回滚到保存点, 外层继续提交
+	err = WithTx(ctx, db, func(ctx context.Context) error {
+		if err := create(ctx, "outer"); err != nil {
+			return err
+		}
+		innerErr := WithTx(ctx, db, func(ctx context.Context) error {
+			if err := create(ctx, "inner"); err != nil {
+				return err
+			}
+			return errors.New("inner failed")
+		})
+		if innerErr == nil {
+			t.Errorf("内层事务应返回错误")
+		}
+		return nil
+	})
+	if err != nil || count() != 1 {
+		t.Fatalf("保存点回滚错误: %v, count = %d", err, count())
+	}
+
+	// 外层失败整体回滚
+	err = WithTx(ctx, db, func(ctx context.Context) error {
+		_ = create(ctx, "rollback")
+		return WithTx(ctx, db, func(ctx context.Context) error { return create(ctx, "nested") })
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	err = WithTx(ctx, db, func(ctx context.Context) error {
+		_ = create(ctx, "rollback")
+		return gorm.ErrInvalidData
+	})
+	if !errors.Is(err, gorm.ErrInvalidData) || count() != 3 {
+		t.Fatalf("外层回滚错误: %v, count = %d", err, count())
+	}
+}
+
+func TestWithTxRetryDeadlock(t *testing.T) {
+	db, err := New(context.Background(), newTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	attempts := 0
+	err = WithTx(context.Background(), db, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return &mysqlDriver.MySQLError{Number: mysqlDeadlock, Message: "Deadlock found"}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("死锁应重试: %v, attempts = %d", err, attempts)
+	}
+
+	attempts = 0
+	err = WithTx(context.Background(), db, func(ctx context.Context) error {
+		attempts++
+		return errors.New("business error")
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("非死锁错误不应重试: %v, attempts = %d", err, attempts)
+	}
+}
@@ -0,0 +1,57 @@
+// Package testutil 包内测试共用的初始化和 miniredis/sqlite 工厂, 只供测试代码使用
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/glebarez/sqlite"
+	goRedis "github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	gormLog "gorm.io/gorm/logger"
+)
+
+// Main 供 TestMain 调用: 初始化输出到临时目录的 error 级别日志, 运行测试后清理目录并退出
+func Main(m *testing.M) {
+	logDir, err := os.MkdirTemp("", "go-core-test")
+	if err != nil {
+		panic(err)
+	}
+	logger.InitializeLogger(&logger.CoreLog{LogDir: logDir, LogLevel: "error"})
+
+	code := m.Run()
+	_ = os.RemoveAll(logDir)
+	os.Exit(code)
+}
+
+// NewRedis 基于 miniredis 的客户端, 测试结束时关闭
+func NewRedis(t testing.TB) (*miniredis.Miniredis, goRedis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goRedis.NewClient(&goRedis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return mr, client
+}
+
+// SQLiteDSN 当前测试独占的内存 sqlite dsn, 同一测试内的连接共享数据
+func SQLiteDSN(t testing.TB) string {
+	return "file:" + t.Name() + "?mode=memory&cache=shared"
+}
+
+// NewSQLite 未注册 go-core 回调的内存 sqlite, 单连接, 测试结束时关闭
+func NewSQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(SQLiteDSN(t)), &gorm.Config{Logger: gormLog.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
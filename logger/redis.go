@@ -0,0 +1,225 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRedisSlowThreshold = 100 * time.Millisecond
+	defaultRedisMaxArgLen     = 64
+	redactedArg               = "***"
+)
+
+// sensitiveRedisCommands 参数中包含密码的命令, 始终脱敏
+var sensitiveRedisCommands = map[string]bool{
+	"auth":    true,
+	"hello":   true,
+	"migrate": true,
+	"acl":     true,
+	"config":  true,
+}
+
+type redisStartKey struct{}
+
+// RedisHookOptions redis命令日志配置
+type RedisHookOptions struct {
+	SlowThreshold time.Duration // SlowThreshold 慢命令阈值, 超过时记录 Warn 日志, 默认100ms, 小于0 关闭
+	LogArgs       bool          // LogArgs 记录完整参数(敏感命令仍脱敏), 默认只记录命令和 key, 其余参数脱敏
+	MaxArgLen     int           // MaxArgLen 单个参数记录的最大长度, 默认64
+}
+
+// RedisCommandStats 单个命令的调用统计
+type RedisCommandStats struct {
+	Calls  int64         // Calls 调用次数
+	Errors int64         // Errors 失败次数, 不含 key 不存在(redis.Nil)
+	Total  time.Duration // Total 累计耗时
+	Max    time.Duration // Max 最大耗时
+}
+
+// Avg 平均耗时
+func (s RedisCommandStats) Avg() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// RedisHook go-redis 命令日志钩子, 与 gorm 的 CustomLogger 对应:
+// 失败命令记录 Error, 慢命令记录 Warn, 其余记录 Debug, 并按命令统计耗时和失败次数
+type RedisHook struct {
+	logger *zap.Logger
+	opts   RedisHookOptions
+
+	mu    sync.Mutex
+	stats map[string]*RedisCommandStats
+}
+
+// NewRedisHook redis命令日志钩子, opts 为 nil 时使用默认配置; 通过 client.AddHook 注册
+func NewRedisHook(zapLogger *zap.Logger, opts *RedisHookOptions) *RedisHook {
+	h := &RedisHook{logger: zapLogger, stats: make(map[string]*RedisCommandStats)}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.SlowThreshold == 0 {
+		h.opts.SlowThreshold = defaultRedisSlowThreshold
+	}
+	if h.opts.MaxArgLen <= 0 {
+		h.opts.MaxArgLen = defaultRedisMaxArgLen
+	}
+	return h
+}
+
+// BeforeProcess 实现 goRedis.Hook
+func (h *RedisHook) BeforeProcess(ctx context.Context, cmd goRedis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+// AfterProcess 实现 goRedis.Hook
+func (h *RedisHook) AfterProcess(ctx context.Context, cmd goRedis.Cmder) error {
+	h.trace(ctx, cmd, elapsedSince(ctx), false)
+	return nil
+}
+
+// BeforeProcessPipeline 实现 goRedis.Hook
+func (h *RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []goRedis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+// AfterProcessPipeline 实现 goRedis.Hook, 管道内每个命令按整个管道的耗时统计
+func (h *RedisHook) AfterProcessPipeline(ctx context.Context, cmds []goRedis.Cmder) error {
+	elapsed := elapsedSince(ctx)
+	for _, cmd := range cmds {
+		h.trace(ctx, cmd, elapsed, true)
+	}
+	return nil
+}
+
+// Stats 按命令名返回统计快照
+func (h *RedisHook) Stats() map[string]RedisCommandStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := make(map[string]RedisCommandStats, len(h.stats))
+	for name, s := range h.stats {
+		result[name] = *s
+	}
+	return result
+}
+
+// ResetStats 清空统计
+func (h *RedisHook) ResetStats() {
+	h.mu.Lock()
+	h.stats = make(map[string]*RedisCommandStats)
+	h.mu.Unlock()
+}
+
+// trace 记录统计和日志
+func (h *RedisHook) trace(ctx context.Context, cmd goRedis.Cmder, elapsed time.Duration, pipeline bool) {
+	name := strings.ToLower(cmd.Name())
+	err := cmd.Err()
+	failed := err != nil && err != goRedis.Nil
+	h.record(name, elapsed, failed)
+
+	slow := h.opts.SlowThreshold > 0 && elapsed >= h.opts.SlowThreshold
+	if !failed && !slow && !h.logger.Core().Enabled(zap.DebugLevel) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.Any(RequestIDKey, ctx.Value(RequestIDKey)),    // requestID
+		zap.String("cmd", h.formatArgs(name, cmd.Args())), // 命令, 参数已脱敏
+		zap.Bool("pipeline", pipeline),                    // 是否管道命令
+		zap.Duration("elapsed", elapsed),                  // 命令耗时
+	}
+	switch {
+	case failed:
+		h.logger.Error("Redis Error", append(fields, zap.Error(err))...)
+	case slow:
+		h.logger.Warn("Redis Slow", fields...)
+	default:
+		h.logger.Debug("Redis Command", fields...)
+	}
+}
+
+// record 累计命令统计
+func (h *RedisHook) record(name string, elapsed time.Duration, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.stats[name]
+	if !ok {
+		s = &RedisCommandStats{}
+		h.stats[name] = s
+	}
+	s.Calls++
+	s.Total += elapsed
+	if elapsed > s.Max {
+		s.Max = elapsed
+	}
+	if failed {
+		s.Errors++
+	}
+}
+
+// formatArgs 格式化命令参数; 默认保留命令名和 key, 其余参数替换为 ***
+func (h *RedisHook) formatArgs(name string, args []interface{}) string {
+	if len(args) == 0 {
+		return name
+	}
+	if sensitiveRedisCommands[name] {
+		return name + " " + redactedArg
+	}
+
+	visible := len(args)
+	if !h.opts.LogArgs {
+		visible = 1 + redisKeyCount(name, args)
+	}
+
+	parts := make([]string, 0, visible+1)
+	parts = append(parts, name)
+	for i := 1; i < visible && i < len(args); i++ {
+		parts = append(parts, h.truncate(fmt.Sprint(args[i])))
+	}
+	if visible < len(args) {
+		parts = append(parts, redactedArg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// truncate 截断过长的参数
+func (h *RedisHook) truncate(s string) string {
+	if len(s) <= h.opts.MaxArgLen {
+		return s
+	}
+	return s[:h.opts.MaxArgLen] + "...(" + strconv.Itoa(len(s)) + " bytes)"
+}
+
+// redisKeyCount 命令名之后连续的 key 参数个数(EVAL/EVALSHA 包含脚本和 key 数量参数)
+func redisKeyCount(name string, args []interface{}) int {
+	switch name {
+	case "eval", "evalsha":
+		if len(args) < 3 {
+			return len(args) - 1
+		}
+		n, _ := strconv.Atoi(fmt.Sprint(args[2]))
+		return 2 + n
+	case "ping", "info", "dbsize", "flushdb", "flushall", "scan", "multi", "exec", "discard", "select":
+		return len(args) - 1
+	default:
+		return 1
+	}
+}
+
+// elapsedSince BeforeProcess 记录的开始时间至今的耗时
+func elapsedSince(ctx context.Context) time.Duration {
+	if start, ok := ctx.Value(redisStartKey{}).(time.Time); ok {
+		return time.Since(start)
+	}
+	return 0
+}
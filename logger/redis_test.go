@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedisHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goRedis.NewClient(&goRedis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	hook := NewRedisHook(zap.New(core), &RedisHookOptions{SlowThreshold: -1})
+	client.AddHook(hook)
+
+	ctx := context.WithValue(context.Background(), RequestIDKey, "req-1")
+	_ = client.Set(ctx, "user:1", "secret-value", time.Minute).Err()
+	_ = client.Get(ctx, "missing").Err()
+	_ = client.Do(ctx, "auth", "password").Err()
+	pipe := client.Pipeline()
+	pipe.Incr(ctx, "counter")
+	pipe.Incr(ctx, "counter")
+	_, _ = pipe.Exec(ctx)
+
+	entries := logs.All()
+	if len(entries) != 5 {
+		t.Fatalf("日志条数 = %d, want 5", len(entries))
+	}
+	set := entries[0].ContextMap()
+	if set["cmd"] != "set user:1 ***" || set[RequestIDKey] != "req-1" || entries[0].Level != zapcore.DebugLevel {
+		t.Fatalf("set 日志 = %v", set)
+	}
+	if cmd := entries[2].ContextMap()["cmd"]; entries[2].Level != zapcore.ErrorLevel || strings.Contains(cmd.(string), "password") {
+		t.Fatalf("auth 应记录错误且参数脱敏: %v", cmd)
+	}
+	if entries[3].ContextMap()["pipeline"] != true {
+		t.Fatalf("管道命令应标记 pipeline")
+	}
+
+	stats := hook.Stats()
+	if stats["incr"].Calls != 2 || stats["get"].Errors != 0 || stats["auth"].Errors != 1 {
+		t.Fatalf("统计错误: %+v", stats)
+	}
+}
+
+func TestRedisHookSlow(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	hook := NewRedisHook(zap.New(core), &RedisHookOptions{SlowThreshold: time.Nanosecond, LogArgs: true, MaxArgLen: 4})
+
+	cmd := goRedis.NewStatusCmd(context.Background(), "set", "user:1", "abcdefgh")
+	ctx, _ := hook.BeforeProcess(context.Background(), cmd)
+	time.Sleep(time.Millisecond)
+	_ = hook.AfterProcess(ctx, cmd)
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zapcore.WarnLevel {
+		t.Fatalf("慢命令应记录 Warn: %+v", entries)
+	}
+	if cmd := entries[0].ContextMap()["cmd"]; cmd != "set user...(6 bytes) abcd...(8 bytes)" {
+		t.Fatalf("cmd = %v", cmd)
+	}
+}
@@ -12,17 +12,18 @@ import (
 )
 
 const (
-	CaptchaKeyPre = "captcha"
+	// CaptchaKeyPre 验证码 key 前缀, 完整 key 为 [namespace:]captcha:<id>
+	CaptchaKeyPre = cacheCtl.KeyCaptcha
 )
 
 // RedisStore 自定义的 Redis 存储器
 type RedisStore struct {
-	client     *redis.Client
+	client     redis.UniversalClient
 	Expiration time.Duration
 }
 
-// NewRedisStore 创建一个新的 Redis 存储器实例
-func NewRedisStore(client *redis.Client, expiration time.Duration) *RedisStore {
+// NewRedisStore 创建一个新的 Redis 存储器实例, 支持单节点/哨兵/集群客户端
+func NewRedisStore(client redis.UniversalClient, expiration time.Duration) *RedisStore {
 	return &RedisStore{
 		client:     client,
 		Expiration: expiration,
@@ -32,7 +33,7 @@ func NewRedisStore(client *redis.Client, expiration time.Duration) *RedisStore {
 // base64Captcha.Store Set 将验证码 ID 和对应的值保存到 Redis 中
 func (r *RedisStore) Set(id string, value string) error {
 	ctx := r.client.Context()
-	key := CaptchaKeyPre + ":" + id
+	key := cacheCtl.Key(CaptchaKeyPre, id)
 	err := r.client.Set(ctx, key, value, r.Expiration).Err()
 	if err != nil {
 		fmt.Printf("Error setting captcha in Redis: %v\n", err)
@@ -45,7 +46,7 @@ func (r *RedisStore) Set(id string, value string) error {
 // base64Captcha.Store Get 根据验证码 ID 从 Redis 中获取对应的值，并可选择是否在获取后清除该值
 func (r *RedisStore) Get(id string, clear bool) string {
 	ctx := r.client.Context()
-	key := CaptchaKeyPre + ":" + id
+	key := cacheCtl.Key(CaptchaKeyPre, id)
 	value, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		return ""
@@ -59,7 +60,7 @@ func (r *RedisStore) Get(id string, clear bool) string {
 // base64Captcha.Store Verify 验证用户输入的答案是否与 Redis 中的值匹配，并可选择是否在验证后清除该值
 func (r *RedisStore) Verify(id, answer string, clear bool) bool {
 	ctx := r.client.Context()
-	key := CaptchaKeyPre + ":" + id
+	key := cacheCtl.Key(CaptchaKeyPre, id)
 	value, err := r.client.Get(ctx, key).Result()
 	if err != nil {
 		return false
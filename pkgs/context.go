@@ -0,0 +1,54 @@
+package pkgs
+
+import "context"
+
+const (
+	// UsernameKey 当前用户名在 gin.Context 中的 key, 由 jwt_token.TokenVerify 设置
+	UsernameKey = "username"
+	// TenantKey 当前租户在 gin.Context 中的 key, 由 web_middleware.TenantMiddleware 设置
+	TenantKey = "tenant_id"
+)
+
+// usernameCtxKey 当前用户名 context key
+type usernameCtxKey struct{}
+
+// tenantCtxKey 当前租户 context key
+type tenantCtxKey struct{}
+
+// WithUsername 将当前用户名写入 ctx
+func WithUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameCtxKey{}, username)
+}
+
+// UsernameFromContext 获取当前用户名, 优先使用 WithUsername 写入的值, 其次兼容 gin.Context 中的 "username"
+func UsernameFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if username, ok := ctx.Value(usernameCtxKey{}).(string); ok && username != "" {
+		return username, true
+	}
+	if username, ok := ctx.Value(UsernameKey).(string); ok && username != "" {
+		return username, true
+	}
+	return "", false
+}
+
+// WithTenant 将当前租户写入 ctx
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// TenantFromContext 获取当前租户, 优先使用 WithTenant 写入的值, 其次兼容 gin.Context 中的 "tenant_id"
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	if tenantID, ok := ctx.Value(tenantCtxKey{}).(string); ok && tenantID != "" {
+		return tenantID, true
+	}
+	if tenantID, ok := ctx.Value(TenantKey).(string); ok && tenantID != "" {
+		return tenantID, true
+	}
+	return "", false
+}
@@ -1,6 +1,17 @@
 package pkgs
 
-import "strings"
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrConflict 并发修改冲突, 例如乐观锁版本号不一致; web 层转换为 409
+	ErrConflict = errors.New("conflict")
+	// ErrNotFound 记录不存在, 例如缓存负命中; web 层转换为 404
+	ErrNotFound = errors.New("not found")
+)
 
 // IsNoRowFoundError gorm 'record not found' 错误处理
 func IsNoRowFoundError(err error) bool {
@@ -25,3 +36,27 @@ func IsRedisNilError(err error) bool {
 	}
 	return false
 }
+
+// IsNotFoundError 是否为记录不存在错误, 包括 ErrNotFound、gorm 'record not found' 和 redis 'redis: nil'
+func IsNotFoundError(err error) bool {
+	return errors.Is(err, ErrNotFound) || IsNoRowFoundError(err) || IsRedisNilError(err)
+}
+
+// IsConflictError 是否为并发修改冲突错误
+func IsConflictError(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// HTTPStatus 错误对应的 http 状态码: 冲突 409, 记录不存在 404, 其他 500
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case IsConflictError(err):
+		return http.StatusConflict
+	case IsNotFoundError(err):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,129 @@
+package pkgs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHealthTimeout = 3 * time.Second
+)
+
+// HealthChecker 依赖健康检查, database 和 redis 包提供实现
+type HealthChecker interface {
+	// Name 依赖名称, 例如 mysql/redis
+	Name() string
+	// Check 检查依赖是否可用, 超时由 ctx 控制
+	Check(ctx context.Context) error
+	// Stats 连接池统计
+	Stats() PoolStats
+}
+
+// PoolStats 连接池统计, 统一 sql.DBStats 和 go-redis PoolStats
+type PoolStats struct {
+	MaxOpen      int           `json:"max_open"`      // MaxOpen 最大连接数, 0 表示不限制或未知
+	Open         int           `json:"open"`          // Open 当前连接数
+	InUse        int           `json:"in_use"`        // InUse 使用中的连接数
+	Idle         int           `json:"idle"`          // Idle 空闲连接数
+	WaitCount    int64         `json:"wait_count"`    // WaitCount 累计等待空闲连接次数
+	WaitDuration time.Duration `json:"wait_duration"` // WaitDuration 累计等待时间
+	Timeouts     int64         `json:"timeouts"`      // Timeouts 累计获取连接超时次数
+}
+
+// HealthStatus 健康检查结果
+type HealthStatus struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+	Stats   PoolStats     `json:"stats"`
+}
+
+// CheckHealth 并发检查所有依赖, 每个依赖的超时为 timeout (默认3s), 结果顺序与 checkers 一致
+func CheckHealth(ctx context.Context, timeout time.Duration, checkers ...HealthChecker) []HealthStatus {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	list := make([]HealthStatus, len(checkers))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker HealthChecker) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			begin := time.Now()
+			err := checker.Check(checkCtx)
+			list[i] = HealthStatus{
+				Name:    checker.Name(),
+				Healthy: err == nil,
+				Latency: time.Since(begin),
+				Stats:   checker.Stats(),
+			}
+			if err != nil {
+				list[i].Error = err.Error()
+			}
+		}(i, checker)
+	}
+	wg.Wait()
+	return list
+}
+
+// StartPoolStatsLogger 每隔 interval 输出连接池统计, 等待次数或超时次数增长、连接池用满时输出告警; 返回停止函数
+func StartPoolStatsLogger(interval time.Duration, checkers ...HealthChecker) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := make([]PoolStats, len(checkers))
+		for i, checker := range checkers {
+			last[i] = checker.Stats()
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			for i, checker := range checkers {
+				stats := checker.Stats()
+				fields := []zap.Field{
+					zap.String("name", checker.Name()),
+					zap.Int("max_open", stats.MaxOpen),
+					zap.Int("open", stats.Open),
+					zap.Int("in_use", stats.InUse),
+					zap.Int("idle", stats.Idle),
+					zap.Int64("wait_count", stats.WaitCount),
+					zap.Duration("wait_duration", stats.WaitDuration),
+					zap.Int64("timeouts", stats.Timeouts),
+				}
+
+				waits, timeouts := stats.WaitCount-last[i].WaitCount, stats.Timeouts-last[i].Timeouts
+				last[i] = stats
+				switch {
+				case waits > 0 || timeouts > 0:
+					logger.Logger.Warn("PoolStats", append(fields, zap.String("msg", "连接池等待次数增长"), zap.Int64("new_waits", waits), zap.Int64("new_timeouts", timeouts))...)
+				case stats.MaxOpen > 0 && stats.InUse >= stats.MaxOpen:
+					logger.Logger.Warn("PoolStats", append(fields, zap.String("msg", "连接池已用满"))...)
+				default:
+					logger.Logger.Info("PoolStats", fields...)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
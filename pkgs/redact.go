@@ -0,0 +1,14 @@
+package pkgs
+
+const (
+	// RedactedMask 脱敏占位符
+	RedactedMask = "***"
+)
+
+// RedactSecret 对敏感信息脱敏, 非空时统一替换为占位符
+func RedactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return RedactedMask
+}
@@ -6,6 +6,9 @@ type ResultInfo struct {
 	Msg   string `json:"msg"`
 	Total int64  `json:"total,omitempty"`
 	Data  any    `json:"data"`
+
+	NextCursor string `json:"next_cursor,omitempty"` // NextCursor 游标分页下一页游标
+	PrevCursor string `json:"prev_cursor,omitempty"` // PrevCursor 游标分页上一页游标
 }
 
 // ReqQuery 列表查询参数
@@ -13,4 +16,6 @@ type ReqQuery struct {
 	Key    string `json:"key"`
 	Offset int    `json:"offset"`
 	Limit  int    `json:"limit"`
+	Sort   string `json:"sort"`   // Sort 排序字段, 逗号分隔, "-" 前缀为倒序, 例如 "-created_at,id"
+	Cursor string `json:"cursor"` // Cursor 游标分页游标, 为空时返回第一页; 使用游标时忽略 Offset
 }
@@ -185,7 +185,7 @@ func exampleUpy() {
 		LocalFilePath: "",
 		FileName:      "",
 	})
-	fmt.Printf("uploadResult: ", uploadResult)
+	fmt.Printf("uploadResult: %v\n", uploadResult)
 }
 
 // 参数注释
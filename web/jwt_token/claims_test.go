@@ -0,0 +1,67 @@
+package jwt_token
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCustomClaims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, j := newTestJWT(t)
+	j.Issuer, j.Audience = "shop", "shop-api"
+
+	pair, err := j.IssuePairWithClaims(Claims{
+		Username: "alice",
+		UserID:   "42",
+		Roles:    []string{"admin"},
+		TenantID: "t1",
+		Extra:    map[string]any{"plan": "pro"},
+	})
+	if err != nil {
+		t.Fatalf("IssuePairWithClaims: %v", err)
+	}
+
+	// 刷新后沿用自定义 claims
+	pair, err = j.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	var got *Claims
+	var tenant string
+	r := gin.New()
+	r.GET("/me", j.TokenVerify(), func(c *gin.Context) {
+		got, _ = GetClaims(c)
+		if claims, ok := ClaimsFromContext(c.Request.Context()); !ok || claims != got {
+			t.Errorf("request ctx 中应有 claims")
+		}
+		tenant = TenantFromClaims(c)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", pair.AccessToken)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || got == nil {
+		t.Fatalf("TokenVerify: %d %s", w.Code, w.Body.String())
+	}
+	if got.UserID != "42" || !got.HasRole("admin") || got.Extra["plan"] != "pro" || tenant != "t1" {
+		t.Fatalf("claims = %+v", got)
+	}
+	if got.Issuer != "shop" || len(got.Audience) != 1 || got.Audience[0] != "shop-api" {
+		t.Fatalf("注册字段 = %+v", got.RegisteredClaims)
+	}
+
+	// 签发方或接收方不一致时校验失败
+	other := &CoreJWT{Secret: j.Secret, Timeout: 60, Issuer: "shop", Audience: "admin-api"}
+	if _, err = other.ParseToken(pair.AccessToken); err == nil {
+		t.Fatalf("接收方不一致应校验失败")
+	}
+	other.Issuer, other.Audience = "", ""
+	if _, err = other.ParseToken(pair.AccessToken); err == nil {
+		t.Fatalf("签发方不一致应校验失败")
+	}
+}
@@ -0,0 +1,28 @@
+package jwt_token
+
+import "context"
+
+// ClaimsKey 当前 token claims 在 gin.Context 中的 key, 由 TokenVerify 设置
+const ClaimsKey = "jwt_claims"
+
+// claimsCtxKey 当前 token claims context key
+type claimsCtxKey struct{}
+
+// WithClaims 将 claims 写入 ctx
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// ClaimsFromContext 获取 claims, 优先使用 WithClaims 写入的值, 其次兼容 gin.Context 中的 ClaimsKey
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	if claims, ok := ctx.Value(claimsCtxKey{}).(*Claims); ok && claims != nil {
+		return claims, true
+	}
+	if claims, ok := ctx.Value(ClaimsKey).(*Claims); ok && claims != nil {
+		return claims, true
+	}
+	return nil, false
+}
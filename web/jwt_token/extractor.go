@@ -0,0 +1,135 @@
+package jwt_token
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+)
+
+// RFC 6750 错误码
+const (
+	AuthErrInvalidRequest    = "invalid_request"    // AuthErrInvalidRequest 请求格式错误, 400
+	AuthErrInvalidToken      = "invalid_token"      // AuthErrInvalidToken token 无效、过期或已注销, 401
+	AuthErrInsufficientScope = "insufficient_scope" // AuthErrInsufficientScope 权限不足, 403
+
+	bearerScheme = "Bearer"
+)
+
+// TokenExtractor 从请求中提取 token, 返回空字符串表示没有 token; 请求格式错误时返回 error
+type TokenExtractor func(c *gin.Context) (string, error)
+
+// FromAuthorization 从 Authorization 请求头提取 "Bearer <token>";
+// 为兼容旧客户端, 不带 scheme 的裸 token 也被接受, 其他 scheme 返回错误
+func FromAuthorization() TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		header := strings.TrimSpace(c.GetHeader("Authorization"))
+		if header == "" {
+			return "", nil
+		}
+		scheme, token, found := strings.Cut(header, " ")
+		if !found {
+			return header, nil
+		}
+		if !strings.EqualFold(scheme, bearerScheme) {
+			return "", &AuthError{Status: http.StatusBadRequest, Code: AuthErrInvalidRequest, Description: "unsupported authorization scheme"}
+		}
+		return strings.TrimSpace(token), nil
+	}
+}
+
+// FromHeader 从自定义请求头提取 token, 值可带 Bearer 前缀
+func FromHeader(name string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		value := strings.TrimSpace(c.GetHeader(name))
+		if len(value) > len(bearerScheme) && strings.EqualFold(value[:len(bearerScheme)+1], bearerScheme+" ") {
+			value = strings.TrimSpace(value[len(bearerScheme)+1:])
+		}
+		return value, nil
+	}
+}
+
+// FromCookie 从 cookie 提取 token
+func FromCookie(name string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		value, _ := c.Cookie(name)
+		return value, nil
+	}
+}
+
+// FromQuery 从查询参数提取 token, 用于浏览器无法设置请求头的 WebSocket 握手; token 可能出现在访问日志中, 只应在必要的路由使用
+func FromQuery(name string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		return c.Query(name), nil
+	}
+}
+
+// AuthError RFC 6750 认证错误, 响应时写入 WWW-Authenticate
+type AuthError struct {
+	Status      int    // Status http 状态码
+	Code        string // Code 错误码 invalid_request/invalid_token/insufficient_scope, 为空表示没有提供凭证
+	Description string // Description 错误说明
+	Scope       string // Scope 所需权限, insufficient_scope 时使用
+}
+
+// Error 实现 error
+func (e *AuthError) Error() string {
+	if e.Code == "" {
+		return e.Description
+	}
+	return e.Code + ": " + e.Description
+}
+
+// AbortWithAuthError 按 RFC 6750 写入 WWW-Authenticate 和 ResultInfo 并终止请求, msg 为返回给调用方的提示
+func AbortWithAuthError(c *gin.Context, realm string, authErr *AuthError, msg string) {
+	c.Header("WWW-Authenticate", authErr.challenge(realm))
+	c.AbortWithStatusJSON(authErr.Status, pkgs.ResultInfo{Code: "-1", Msg: msg})
+}
+
+// challenge WWW-Authenticate 的值, 例如 Bearer realm="api", error="invalid_token", error_description="token expired"
+func (e *AuthError) challenge(realm string) string {
+	params := make([]string, 0, 4)
+	if realm != "" {
+		params = append(params, authParam("realm", realm))
+	}
+	if e.Code != "" {
+		params = append(params, authParam("error", e.Code))
+		if e.Description != "" {
+			params = append(params, authParam("error_description", e.Description))
+		}
+	}
+	if e.Scope != "" {
+		params = append(params, authParam("scope", e.Scope))
+	}
+	if len(params) == 0 {
+		return bearerScheme
+	}
+	return bearerScheme + " " + strings.Join(params, ", ")
+}
+
+// authParam 参数值只保留 RFC 6750 允许的可见 ASCII 字符, 去掉引号和反斜杠
+func authParam(name, value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, value)
+	return name + `="` + value + `"`
+}
+
+// extractToken 按顺序尝试 extractors, 返回第一个非空 token
+func (h *CoreJWT) extractToken(c *gin.Context) (string, error) {
+	extractors := h.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{FromAuthorization()}
+	}
+	for _, extract := range extractors {
+		token, err := extract(c)
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,58 @@
+package jwt_token
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenExtractors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, j := newTestJWT(t)
+	j.Realm = "api"
+	j.Extractors = []TokenExtractor{FromAuthorization(), FromHeader("X-Token"), FromCookie("token"), FromQuery("access_token")}
+	pair, _ := j.IssuePair("alice")
+
+	r := gin.New()
+	r.GET("/me", j.TokenVerify(), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	cases := []struct {
+		name      string
+		setup     func(req *http.Request)
+		status    int
+		challenge string
+	}{
+		{"bearer", func(req *http.Request) { req.Header.Set("Authorization", "bearer "+pair.AccessToken) }, http.StatusOK, ""},
+		{"裸 token 兼容", func(req *http.Request) { req.Header.Set("Authorization", pair.AccessToken) }, http.StatusOK, ""},
+		{"header", func(req *http.Request) { req.Header.Set("X-Token", "Bearer "+pair.AccessToken) }, http.StatusOK, ""},
+		{"cookie", func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "token", Value: pair.AccessToken}) }, http.StatusOK, ""},
+		{"query", func(req *http.Request) { req.URL.RawQuery = "access_token=" + pair.AccessToken }, http.StatusOK, ""},
+		{"缺少 token", func(req *http.Request) {}, http.StatusUnauthorized, `Bearer realm="api"`},
+		{"scheme 错误", func(req *http.Request) { req.Header.Set("Authorization", "Basic abc") }, http.StatusBadRequest, `Bearer realm="api", error="invalid_request"`},
+		{"token 无效", func(req *http.Request) { req.Header.Set("Authorization", "Bearer abc") }, http.StatusUnauthorized, `Bearer realm="api", error="invalid_token"`},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		tc.setup(req)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, w.Code, tc.status)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tc.challenge) || (tc.challenge == "") != (got == "") {
+			t.Errorf("%s: WWW-Authenticate = %q, want prefix %q", tc.name, got, tc.challenge)
+		}
+	}
+}
+
+func TestAuthErrorChallenge(t *testing.T) {
+	e := &AuthError{Status: http.StatusForbidden, Code: AuthErrInsufficientScope, Description: `need "admin"`, Scope: "order:write"}
+	want := `Bearer error="insufficient_scope", error_description="need admin", scope="order:write"`
+	if got := e.challenge(""); got != want {
+		t.Fatalf("challenge = %q, want %q", got, want)
+	}
+}
@@ -1,64 +1,99 @@
 package jwt_token
 
 import (
-	"context"
+	"errors"
 	"net/http"
 
 	"github.com/bigbigliu/go-core/database/redis"
 	"github.com/bigbigliu/go-core/pkgs"
 	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
 )
 
+// TokenVerify token 校验中间件, 按 Extractors 顺序提取 token (默认 Authorization: Bearer), 失败时按 RFC 6750 返回 WWW-Authenticate;
+// 通过后将用户名和完整 claims 写入 gin.Context 和 request ctx, 使用 GetClaims/ClaimsFromContext 读取
 func (h *CoreJWT) TokenVerify() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		res := pkgs.ResultInfo{}
-		token := c.Request.Header.Get("Authorization")
+		token, err := h.extractToken(c)
+		if err != nil {
+			authErr, ok := err.(*AuthError)
+			if !ok {
+				authErr = &AuthError{Status: http.StatusBadRequest, Code: AuthErrInvalidRequest, Description: err.Error()}
+			}
+			AbortWithAuthError(c, h.Realm, authErr, err.Error())
+			return
+		}
 		if token == "" {
-			res.Msg = "token不能为空"
-			res.Code = "-1"
-			c.JSON(http.StatusUnauthorized, res)
-			c.Abort()
+			AbortWithAuthError(c, h.Realm, &AuthError{Status: http.StatusUnauthorized}, "token不能为空")
 			return
 		}
 
 		claims, err := h.ParseToken(token)
-		if err != nil {
-			res.Msg = err.Error()
-			res.Code = "-1"
-			c.JSON(http.StatusUnauthorized, res)
-			c.Abort()
+		if err != nil || claims == nil {
+			msg := "token解析失败"
+			if err != nil {
+				msg = err.Error()
+			}
+			h.abortInvalidToken(c, "token is malformed, expired or has an invalid signature", msg)
 			return
 		}
 
-		if claims == nil {
-			res.Msg = "token解析失败"
-			res.Code = "-1"
-			c.JSON(http.StatusUnauthorized, res)
-			c.Abort()
+		if claims.TokenType == TokenTypeRefresh {
+			h.abortInvalidToken(c, "refresh token cannot be used as access token", "refresh token不能用于访问接口")
 			return
 		}
 
 		username := claims.Username
-		token_key := "coreos:" + username + ":accesstoken"
-
-		tokenStr, err := redis.Redisclient.Get(context.Background(), token_key).Result()
-		if err != nil {
-			res.Msg = "token解析失败"
-			res.Code = "-1"
-			c.JSON(http.StatusUnauthorized, res)
-			c.Abort()
+		if err = h.store().Touch(c.Request.Context(), username, claims.SessionID); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				h.abortInvalidToken(c, "token has been revoked or expired", "token过期")
+			} else {
+				internalError(c, err)
+			}
 			return
 		}
 
-		if tokenStr == "" {
-			res.Msg = "token过期"
-			res.Code = "-1"
-			c.JSON(http.StatusUnauthorized, res)
-			c.Abort()
-			return
-		}
-
-		c.Set("username", username)
+		c.Set(pkgs.UsernameKey, username)
+		c.Set(ClaimsKey, claims)
+		ctx := pkgs.WithUsername(c.Request.Context(), username)
+		c.Request = c.Request.WithContext(WithClaims(ctx, claims))
 		c.Next()
 	}
 }
+
+// abortInvalidToken 返回 401 invalid_token
+func (h *CoreJWT) abortInvalidToken(c *gin.Context, description, msg string) {
+	AbortWithAuthError(c, h.Realm, &AuthError{Status: http.StatusUnauthorized, Code: AuthErrInvalidToken, Description: description}, msg)
+}
+
+// GetClaims 获取 TokenVerify 写入 gin.Context 的 claims
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok && claims != nil
+}
+
+// TenantFromClaims 从 claims 提取租户, web_middleware.TenantMiddleware 的默认 TenantExtractor, 需在 TokenVerify 之后
+func TenantFromClaims(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok {
+		return claims.TenantID
+	}
+	return ""
+}
+
+// TokenKey 旧版(不带 sid) access token 在 redis 中的 key, 完整 key 为 [namespace:]coreos:<username>:accesstoken;
+// NewToken 签发的 token 由业务写入该 key, RedisStore 据此校验
+func TokenKey(username string) string {
+	return redis.Key(redis.KeyToken, username, "accesstoken")
+}
+
+// redisClient token 存储客户端
+func (h *CoreJWT) redisClient() goRedis.UniversalClient {
+	if h.Redis != nil {
+		return h.Redis
+	}
+	return redis.Redisclient
+}
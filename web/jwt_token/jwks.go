@@ -0,0 +1,76 @@
+package jwt_token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSPath JWKS 接口的标准路径
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWK RFC 7517 公钥
+type JWK struct {
+	Kty string `json:"kty"`           // Kty 密钥类型 RSA/EC/OKP
+	Kid string `json:"kid"`           // Kid 密钥标识
+	Use string `json:"use"`           // Use 固定为 sig
+	Alg string `json:"alg"`           // Alg 签名算法
+	N   string `json:"n,omitempty"`   // N RSA 模数
+	E   string `json:"e,omitempty"`   // E RSA 指数
+	Crv string `json:"crv,omitempty"` // Crv 曲线 P-256/Ed25519
+	X   string `json:"x,omitempty"`   // X 曲线坐标
+	Y   string `json:"y,omitempty"`   // Y 曲线坐标
+}
+
+// JWKS RFC 7517 公钥集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出所有非对称密钥的公钥, HS256 共享密钥不导出
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range s.Keys() {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch public := key.publicKey().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBase64URL(public.N.Bytes())
+			jwk.E = encodeBase64URL(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty, jwk.Crv = "EC", public.Curve.Params().Name
+			jwk.X = encodeBase64URL(public.X.FillBytes(make([]byte, size)))
+			jwk.Y = encodeBase64URL(public.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = encodeBase64URL(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// JWKSHandler JWKS 接口, 挂载在 JWKSPath 供其他服务校验 token; 未配置 Keys 时返回空集合
+func (c *CoreJWT) JWKSHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		set := JWKS{Keys: []JWK{}}
+		if c.Keys != nil {
+			set = c.Keys.JWKS()
+		}
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, set)
+	}
+}
+
+// encodeBase64URL base64url 无填充编码
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
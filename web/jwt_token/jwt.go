@@ -1,8 +1,11 @@
 package jwt_token
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
+	goRedis "github.com/go-redis/redis/v8"
 	goJwt "github.com/golang-jwt/jwt/v5"
 )
 
@@ -12,44 +15,60 @@ type ICoreJwt interface {
 	ParseToken(token string) (*Claims, error)
 }
 
+const defaultIssuer = "core_os"
+
 // Claims ...
 type Claims struct {
-	Username string `json:"username"`
+	Username  string         `json:"username"`
+	UserID    string         `json:"user_id,omitempty"`    // UserID 用户ID
+	Roles     []string       `json:"roles,omitempty"`      // Roles 用户角色
+	TenantID  string         `json:"tenant_id,omitempty"`  // TenantID 用户所属租户, 可通过 TenantFromClaims 供租户中间件使用
+	Extra     map[string]any `json:"extra,omitempty"`      // Extra 业务自定义字段, 数字解析后为 float64
+	TokenType string         `json:"token_type,omitempty"` // TokenType access/refresh, NewToken 签发的 token 为空, 按 access 处理
+	SessionID string         `json:"sid,omitempty"`        // SessionID 会话ID, 同一次登录签发和刷新轮换出的 token 属于同一会话; NewToken 签发的 token 为空
 	goJwt.RegisteredClaims
 }
 
+// HasRole 是否拥有角色
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
 // CoreJWT ...
 type CoreJWT struct {
-	Secret  string `json:"secret"`  // jwt密钥
-	Timeout int    `json:"timeout"` // jwt过期时间
+	Secret         string `json:"secret"`          // jwt密钥
+	Timeout        int    `json:"timeout"`         // jwt过期时间
+	RefreshTimeout int    `json:"refresh_timeout"` // RefreshTimeout refresh token 过期时间 单位 秒/s, 默认7天
+	IdleTimeout    int    `json:"idle_timeout"`    // IdleTimeout 会话滑动过期时间 单位 秒/s, 超过该时间未访问会话即失效, 0 不启用
+	Issuer         string `json:"issuer"`          // Issuer 签发方, 默认 core_os, 解析时校验
+	Audience       string `json:"audience"`        // Audience 接收方, 为空时不写入也不校验
+
+	Realm string `json:"realm"` // Realm WWW-Authenticate 的 realm, 为空时不输出
+
+	Extractors []TokenExtractor        `json:"-"` // Extractors token 提取方式, 按顺序尝试, 默认 FromAuthorization
+	Keys       *KeySet                 `json:"-"` // Keys 签名密钥集, 为空时使用 Secret 以 HS256 签名; 配置后使用最新密钥签名并在头部写入 kid
+	Store      TokenStore              `json:"-"` // Store 会话存储, 为空时使用基于 Redis 的 RedisStore
+	Redis      goRedis.UniversalClient `json:"-"` // Redis 默认 RedisStore 的客户端, 支持单节点/哨兵/集群, 为空时使用 redis.Redisclient
 }
 
 // NewToken 生成新token
 func (c *CoreJWT) NewToken(username string) (string, error) {
-	nowTime := time.Now()
-	expireTime := nowTime.Add(time.Duration(c.Timeout) * time.Second)
-
-	claims := Claims{
-		Username: username,
-		RegisteredClaims: goJwt.RegisteredClaims{
-			Issuer:    "core_os",                        // 发签人
-			ExpiresAt: goJwt.NewNumericDate(expireTime), // 过期时间
-			NotBefore: goJwt.NewNumericDate(nowTime),    // 生效时间
-			IssuedAt:  goJwt.NewNumericDate(nowTime),    // 签发时间
-		},
-	}
+	return c.NewTokenWithClaims(Claims{Username: username})
+}
 
-	t := goJwt.NewWithClaims(goJwt.SigningMethodHS256, claims)
-	token, err := t.SignedString([]byte(c.Secret))
-	return token, err
+// NewTokenWithClaims 使用自定义 claims 生成新token, 签发方、接收方、过期时间等注册字段由 CoreJWT 填充
+func (c *CoreJWT) NewTokenWithClaims(claims Claims) (string, error) {
+	return c.sign(c.newClaims(claims, "", "", "", time.Now(), time.Duration(c.Timeout)*time.Second))
 }
 
-// ParseToken 解析token
+// ParseToken 解析token, 只接受 Keys 中的算法和 kid; 没有 kid 的 token 按 HS256 + Secret 校验; 校验签发方和接收方
 func (c *CoreJWT) ParseToken(token string) (*Claims, error) {
-	jwtSecret := []byte(c.Secret)
-	tokenClaims, err := goJwt.ParseWithClaims(token, &Claims{}, func(token *goJwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	opts := []goJwt.ParserOption{goJwt.WithValidMethods(c.algorithms()), goJwt.WithIssuer(c.issuer())}
+	if c.Audience != "" {
+		opts = append(opts, goJwt.WithAudience(c.Audience))
+	}
+	parser := goJwt.NewParser(opts...)
+	tokenClaims, err := parser.ParseWithClaims(token, &Claims{}, c.keyFunc)
 
 	if tokenClaims != nil {
 		if claims, ok := tokenClaims.Claims.(*Claims); ok && tokenClaims.Valid {
@@ -59,3 +78,80 @@ func (c *CoreJWT) ParseToken(token string) (*Claims, error) {
 
 	return nil, err
 }
+
+// sign 使用最新密钥签名, 未配置 Keys 时使用 Secret 以 HS256 签名
+func (c *CoreJWT) sign(claims Claims) (string, error) {
+	if c.Keys == nil {
+		return goJwt.NewWithClaims(goJwt.SigningMethodHS256, claims).SignedString([]byte(c.Secret))
+	}
+
+	key, err := c.Keys.signingKey()
+	if err != nil {
+		return "", err
+	}
+	t := goJwt.NewWithClaims(key.method(), claims)
+	t.Header["kid"] = key.ID
+	return t.SignedString(key.signKey())
+}
+
+// keyFunc 按 kid 选择校验密钥, 并要求 token 的 alg 与密钥一致
+func (c *CoreJWT) keyFunc(token *goJwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if c.Secret == "" || token.Method != goJwt.SigningMethodHS256 {
+			return nil, ErrAlgorithmMismatch
+		}
+		return []byte(c.Secret), nil
+	}
+
+	if c.Keys == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	key, err := c.Keys.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+	return key.verifyKey(), nil
+}
+
+// algorithms 允许的签名算法
+func (c *CoreJWT) algorithms() []string {
+	var algs []string
+	if c.Keys != nil {
+		algs = c.Keys.Algorithms()
+	}
+	if c.Secret != "" && !slices.Contains(algs, AlgHS256) {
+		algs = append(algs, AlgHS256)
+	}
+	return algs
+}
+
+// newClaims 在 base 的自定义字段上填充 token 类型和注册字段
+func (c *CoreJWT) newClaims(base Claims, tokenType, sessionID, id string, nowTime time.Time, ttl time.Duration) Claims {
+	claims := base
+	claims.TokenType = tokenType
+	claims.SessionID = sessionID
+	claims.RegisteredClaims = goJwt.RegisteredClaims{
+		ID:        id,                                     // 唯一标识
+		Issuer:    c.issuer(),                             // 发签人
+		Subject:   base.Subject,                           // 主题
+		ExpiresAt: goJwt.NewNumericDate(nowTime.Add(ttl)), // 过期时间
+		NotBefore: goJwt.NewNumericDate(nowTime),          // 生效时间
+		IssuedAt:  goJwt.NewNumericDate(nowTime),          // 签发时间
+	}
+	if c.Audience != "" {
+		claims.Audience = goJwt.ClaimStrings{c.Audience}
+	}
+	return claims
+}
+
+// issuer 签发方
+func (c *CoreJWT) issuer() string {
+	if c.Issuer != "" {
+		return c.Issuer
+	}
+	return defaultIssuer
+}
@@ -0,0 +1,274 @@
+package jwt_token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	goJwt "github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256" // AlgHS256 HMAC-SHA256, 共享密钥
+	AlgRS256 = "RS256" // AlgRS256 RSA-SHA256
+	AlgES256 = "ES256" // AlgES256 ECDSA P-256
+	AlgEdDSA = "EdDSA" // AlgEdDSA Ed25519
+)
+
+var (
+	// ErrNoSigningKey 密钥集中没有可用于签名的密钥
+	ErrNoSigningKey = errors.New("jwt: no signing key")
+	// ErrUnknownKey token 的 kid 不在密钥集中, 或密钥已停用
+	ErrUnknownKey = errors.New("jwt: unknown key id")
+	// ErrAlgorithmMismatch token 的签名算法与密钥不一致
+	ErrAlgorithmMismatch = errors.New("jwt: algorithm mismatch")
+)
+
+// SigningKey 签名密钥, ID 写入 token 头部的 kid; 只有公钥的密钥只用于校验, 用于轮换后仍需校验旧 token 的场景
+type SigningKey struct {
+	ID        string           // ID 密钥标识 kid
+	Algorithm string           // Algorithm 签名算法 HS256/RS256/ES256/EdDSA
+	Secret    []byte           // Secret HS256 共享密钥
+	Private   crypto.Signer    // Private 私钥 *rsa.PrivateKey/*ecdsa.PrivateKey/ed25519.PrivateKey
+	Public    crypto.PublicKey // Public 公钥, 为空时由 Private 推导
+}
+
+// KeyFile PEM 密钥文件配置
+type KeyFile struct {
+	ID             string `json:"id"`               // ID 密钥标识 kid
+	Algorithm      string `json:"algorithm"`        // Algorithm 签名算法 RS256/ES256/EdDSA
+	PrivateKeyFile string `json:"private_key_file"` // PrivateKeyFile 私钥文件, 为空时该密钥只用于校验
+	PublicKeyFile  string `json:"public_key_file"`  // PublicKeyFile 公钥文件, 配置私钥时可为空
+}
+
+// LoadKeyFile 从 PEM 文件加载密钥, 私钥支持 PKCS1/PKCS8/SEC1, 公钥为 PKIX
+func LoadKeyFile(f KeyFile) (*SigningKey, error) {
+	key := &SigningKey{ID: f.ID, Algorithm: f.Algorithm}
+	if f.PrivateKeyFile != "" {
+		data, err := os.ReadFile(f.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var private crypto.PrivateKey
+		switch f.Algorithm {
+		case AlgRS256:
+			private, err = goJwt.ParseRSAPrivateKeyFromPEM(data)
+		case AlgES256:
+			private, err = goJwt.ParseECPrivateKeyFromPEM(data)
+		case AlgEdDSA:
+			private, err = goJwt.ParseEdPrivateKeyFromPEM(data)
+		default:
+			return nil, fmt.Errorf("jwt: unsupported algorithm %q for key file", f.Algorithm)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt: load private key %s: %w", f.PrivateKeyFile, err)
+		}
+		key.Private = private.(crypto.Signer)
+	}
+
+	if f.PublicKeyFile != "" {
+		data, err := os.ReadFile(f.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Algorithm {
+		case AlgRS256:
+			key.Public, err = goJwt.ParseRSAPublicKeyFromPEM(data)
+		case AlgES256:
+			key.Public, err = goJwt.ParseECPublicKeyFromPEM(data)
+		case AlgEdDSA:
+			key.Public, err = goJwt.ParseEdPublicKeyFromPEM(data)
+		default:
+			return nil, fmt.Errorf("jwt: unsupported algorithm %q for key file", f.Algorithm)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt: load public key %s: %w", f.PublicKeyFile, err)
+		}
+	}
+
+	if err := key.validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// KeySet 密钥集, 使用最新添加的可签名密钥签名, 使用任一未停用的密钥校验
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*SigningKey
+}
+
+// NewKeySet 按顺序添加密钥, 最后一个可签名的密钥用于签名
+func NewKeySet(keys ...*SigningKey) (*KeySet, error) {
+	s := &KeySet{}
+	for _, key := range keys {
+		if err := s.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// NewKeySetFromFiles 从 PEM 文件加载密钥集, 顺序同 NewKeySet
+func NewKeySetFromFiles(files ...KeyFile) (*KeySet, error) {
+	keys := make([]*SigningKey, 0, len(files))
+	for _, f := range files {
+		key, err := LoadKeyFile(f)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(keys...)
+}
+
+// Add 添加密钥, 可签名时成为新的签名密钥; kid 已存在时替换
+func (s *KeySet) Add(key *SigningKey) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(key.ID)
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+// Remove 停用密钥, 之后使用该密钥签名的 token 校验失败
+func (s *KeySet) Remove(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(kid)
+}
+
+// Keys 当前所有密钥, 按添加顺序
+func (s *KeySet) Keys() []*SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*SigningKey(nil), s.keys...)
+}
+
+// Algorithms 密钥集中的签名算法, 用于严格校验 token 的 alg
+func (s *KeySet) Algorithms() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var algs []string
+	seen := make(map[string]bool)
+	for _, key := range s.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algs = append(algs, key.Algorithm)
+		}
+	}
+	return algs
+}
+
+// signingKey 最新的可签名密钥
+func (s *KeySet) signingKey() (*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].canSign() {
+			return s.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// lookup 按 kid 查找密钥
+func (s *KeySet) lookup(kid string) (*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// removeLocked 删除 kid, 调用方持有写锁
+func (s *KeySet) removeLocked(kid string) {
+	for i, key := range s.keys {
+		if key.ID == kid {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+// canSign 是否可用于签名
+func (k *SigningKey) canSign() bool {
+	if k.Algorithm == AlgHS256 {
+		return len(k.Secret) > 0
+	}
+	return k.Private != nil
+}
+
+// method 签名方法
+func (k *SigningKey) method() goJwt.SigningMethod {
+	return goJwt.GetSigningMethod(k.Algorithm)
+}
+
+// signKey 签名使用的密钥
+func (k *SigningKey) signKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.Private
+}
+
+// verifyKey 校验使用的密钥
+func (k *SigningKey) verifyKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.publicKey()
+}
+
+// publicKey 公钥, 未配置时由私钥推导
+func (k *SigningKey) publicKey() crypto.PublicKey {
+	if k.Public == nil && k.Private != nil {
+		return k.Private.Public()
+	}
+	return k.Public
+}
+
+// validate 校验密钥类型与算法一致
+func (k *SigningKey) validate() error {
+	if k == nil || k.ID == "" {
+		return errors.New("jwt: key id is required")
+	}
+	if k.Algorithm == AlgHS256 {
+		if len(k.Secret) == 0 {
+			return fmt.Errorf("jwt: key %q: secret is required", k.ID)
+		}
+		return nil
+	}
+
+	public := k.publicKey()
+	var ok bool
+	switch k.Algorithm {
+	case AlgRS256:
+		_, ok = public.(*rsa.PublicKey)
+	case AlgES256:
+		var ec *ecdsa.PublicKey
+		ec, ok = public.(*ecdsa.PublicKey)
+		ok = ok && ec.Curve == elliptic.P256()
+	case AlgEdDSA:
+		_, ok = public.(ed25519.PublicKey)
+	default:
+		return fmt.Errorf("jwt: key %q: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+	if !ok {
+		return fmt.Errorf("jwt: key %q: key type does not match %s", k.ID, k.Algorithm)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package jwt_token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	goJwt "github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM 写入 PKCS8 私钥和 PKIX 公钥
+func writePEM(t *testing.T, dir, name string, private any, public any) (string, string) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDer, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath, publicPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".pub.pem")
+	_ = os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600)
+	_ = os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0o600)
+	return privatePath, publicPath
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, rsaPub := writePEM(t, dir, "rsa", rsaKey, &rsaKey.PublicKey)
+	ecPriv, _ := writePEM(t, dir, "ec", ecKey, &ecKey.PublicKey)
+	edPriv, _ := writePEM(t, dir, "ed", edKey, edPub)
+
+	// 旧 RSA 密钥只保留公钥用于校验
+	oldSigner, _ := NewKeySet(&SigningKey{ID: "rsa", Algorithm: AlgRS256, Private: rsaKey})
+	old := &CoreJWT{Timeout: 60, Keys: oldSigner}
+	oldToken, err := old.NewToken("alice")
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	keys, err := NewKeySetFromFiles(
+		KeyFile{ID: "rsa", Algorithm: AlgRS256, PublicKeyFile: rsaPub},
+		KeyFile{ID: "ec", Algorithm: AlgES256, PrivateKeyFile: ecPriv},
+		KeyFile{ID: "ed", Algorithm: AlgEdDSA, PrivateKeyFile: edPriv},
+	)
+	if err != nil {
+		t.Fatalf("NewKeySetFromFiles: %v", err)
+	}
+	j := &CoreJWT{Timeout: 60, Keys: keys}
+
+	token, err := j.NewToken("bob")
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	parsed, _, _ := goJwt.NewParser().ParseUnverified(token, &Claims{})
+	if parsed.Header["kid"] != "ed" || parsed.Method.Alg() != AlgEdDSA {
+		t.Fatalf("应使用最新密钥签名: %v", parsed.Header)
+	}
+	if claims, err := j.ParseToken(token); err != nil || claims.Username != "bob" {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	if claims, err := j.ParseToken(oldToken); err != nil || claims.Username != "alice" {
+		t.Fatalf("旧密钥签名的 token 应仍可校验: %v", err)
+	}
+
+	// 停用旧密钥后校验失败
+	keys.Remove("rsa")
+	if _, err = j.ParseToken(oldToken); err == nil {
+		t.Fatalf("停用的密钥签名的 token 应校验失败")
+	}
+	forged := goJwt.NewWithClaims(goJwt.SigningMethodES256, Claims{Username: "mallory"})
+	forged.Header["kid"] = "unknown"
+	forgedToken, _ := forged.SignedString(ecKey)
+	if _, err = j.ParseToken(forgedToken); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("未知 kid 应返回 ErrUnknownKey, got %v", err)
+	}
+
+	if _, err = LoadKeyFile(KeyFile{ID: "bad", Algorithm: AlgRS256, PrivateKeyFile: ecPriv}); err == nil {
+		t.Fatalf("算法与密钥类型不一致应返回错误")
+	}
+}
+
+func TestStrictAlgorithm(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys, _ := NewKeySet(&SigningKey{ID: "rsa", Algorithm: AlgRS256, Private: rsaKey})
+	j := &CoreJWT{Timeout: 60, Keys: keys}
+
+	// 使用公钥作为 HMAC 密钥伪造 token
+	pubDer, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := goJwt.NewWithClaims(goJwt.SigningMethodHS256, Claims{Username: "mallory"})
+	forged.Header["kid"] = "rsa"
+	forgedToken, _ := forged.SignedString(pubDer)
+	if _, err := j.ParseToken(forgedToken); err == nil {
+		t.Fatalf("算法不一致的 token 应校验失败")
+	}
+
+	none, _ := goJwt.NewWithClaims(goJwt.SigningMethodNone, Claims{Username: "mallory"}).SignedString(goJwt.UnsafeAllowNoneSignatureType)
+	if _, err := j.ParseToken(none); err == nil {
+		t.Fatalf("alg=none 的 token 应校验失败")
+	}
+
+	// 兼容未配置 Keys 的 HS256
+	legacy := &CoreJWT{Secret: "secret", Timeout: 60}
+	token, _ := legacy.NewToken("alice")
+	if claims, err := legacy.ParseToken(token); err != nil || claims.Username != "alice" {
+		t.Fatalf("HS256 ParseToken: %v", err)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys, _ := NewKeySet(
+		&SigningKey{ID: "hs", Algorithm: AlgHS256, Secret: []byte("secret")},
+		&SigningKey{ID: "rsa", Algorithm: AlgRS256, Private: rsaKey},
+		&SigningKey{ID: "ec", Algorithm: AlgES256, Public: &ecKey.PublicKey},
+	)
+
+	r := gin.New()
+	r.GET(JWKSPath, (&CoreJWT{Keys: keys}).JWKSHandler())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, JWKSPath, nil))
+
+	var set JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil || len(set.Keys) != 2 {
+		t.Fatalf("JWKS 应只包含非对称公钥: %v, %s", err, w.Body.String())
+	}
+	if k := set.Keys[0]; k.Kid != "rsa" || k.Kty != "RSA" || k.E != "AQAB" {
+		t.Fatalf("RSA JWK = %+v", k)
+	}
+	if k := set.Keys[1]; k.Kty != "EC" || k.Crv != "P-256" || len(k.X) != 43 {
+		t.Fatalf("EC JWK = %+v", k)
+	}
+}
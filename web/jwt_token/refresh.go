@@ -0,0 +1,207 @@
+package jwt_token
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// TokenTypeAccess 访问令牌
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh 刷新令牌
+	TokenTypeRefresh = "refresh"
+
+	defaultRefreshTimeout = 7 * 24 * 3600
+)
+
+var (
+	// ErrInvalidRefreshToken refresh token 无效或已过期
+	ErrInvalidRefreshToken = errors.New("jwt: invalid refresh token")
+	// ErrRefreshTokenRevoked refresh token 所属会话已过期或已注销
+	ErrRefreshTokenRevoked = errors.New("jwt: refresh token revoked")
+	// ErrRefreshTokenReused 已轮换的 refresh token 被再次使用, 所属会话已注销
+	ErrRefreshTokenReused = errors.New("jwt: refresh token reused")
+)
+
+// TokenPair access token 和 refresh token
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`       // AccessToken 访问令牌
+	RefreshToken     string `json:"refresh_token"`      // RefreshToken 刷新令牌, 只能使用一次
+	TokenType        string `json:"token_type"`         // TokenType 固定为 Bearer
+	ExpiresIn        int    `json:"expires_in"`         // ExpiresIn access token 有效期 单位 秒/s
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // RefreshExpiresIn refresh token 有效期 单位 秒/s
+}
+
+// RefreshReq 刷新/注销请求参数
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"` // RefreshToken 刷新令牌
+}
+
+// IssuePair 登录时签发 access token 和 refresh token, 开启新的会话
+func (c *CoreJWT) IssuePair(username string) (*TokenPair, error) {
+	return c.IssuePairWithClaims(Claims{Username: username})
+}
+
+// IssuePairWithClaims 使用自定义 claims 签发 token 对并开启新的会话, 刷新时沿用这些 claims, 角色等变更需重新登录生效
+func (c *CoreJWT) IssuePairWithClaims(claims Claims) (*TokenPair, error) {
+	return c.IssuePairContext(context.Background(), claims)
+}
+
+// IssuePairContext 同 IssuePairWithClaims, ctx 用于会话存储的超时控制和日志追踪
+func (c *CoreJWT) IssuePairContext(ctx context.Context, claims Claims) (*TokenPair, error) {
+	return c.issue(ctx, claims, Session{})
+}
+
+// Refresh 使用 refresh token 换取新的 token 对并延长会话, 旧 refresh token 立即失效;
+// 已失效的 refresh token 被再次使用时视为泄露, 注销整个会话
+func (c *CoreJWT) Refresh(refreshToken string) (*TokenPair, error) {
+	return c.RefreshContext(context.Background(), refreshToken)
+}
+
+// RefreshContext 同 Refresh, ctx 用于会话存储的超时控制和日志追踪
+func (c *CoreJWT) RefreshContext(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := c.parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	pair, jti, err := c.signPair(*claims, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(c.refreshTTL())
+	if err = c.store().Rotate(ctx, claims.Username, claims.SessionID, claims.ID, jti, expiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RevokeRefreshToken 注销 refresh token 所属会话, 用于退出登录
+func (c *CoreJWT) RevokeRefreshToken(refreshToken string) error {
+	return c.RevokeRefreshTokenContext(context.Background(), refreshToken)
+}
+
+// RevokeRefreshTokenContext 同 RevokeRefreshToken, ctx 用于会话存储的超时控制和日志追踪
+func (c *CoreJWT) RevokeRefreshTokenContext(ctx context.Context, refreshToken string) error {
+	claims, err := c.parseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	return c.store().Revoke(ctx, claims.Username, claims.SessionID)
+}
+
+// RefreshHandler 刷新 token 接口, 请求体为 RefreshReq, 成功时 data 为 TokenPair
+func (c *CoreJWT) RefreshHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req RefreshReq
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, pkgs.ResultInfo{Code: "-1", Msg: err.Error()})
+			return
+		}
+
+		pair, err := c.RefreshContext(ctx.Request.Context(), req.RefreshToken)
+		if err != nil {
+			refreshError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok", Data: pair})
+	}
+}
+
+// RevokeHandler 退出登录接口, 请求体为 RefreshReq
+func (c *CoreJWT) RevokeHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req RefreshReq
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, pkgs.ResultInfo{Code: "-1", Msg: err.Error()})
+			return
+		}
+
+		if err := c.RevokeRefreshTokenContext(ctx.Request.Context(), req.RefreshToken); err != nil {
+			refreshError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok"})
+	}
+}
+
+// signPair 签发同一会话的 token 对, 返回 refresh token 的 jti
+func (c *CoreJWT) signPair(base Claims, sessionID string) (*TokenPair, string, error) {
+	accessID, err := newTokenID()
+	if err != nil {
+		return nil, "", err
+	}
+	refreshID, err := newTokenID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	nowTime := time.Now()
+	access, err := c.sign(c.newClaims(base, TokenTypeAccess, sessionID, accessID, nowTime, time.Duration(c.Timeout)*time.Second))
+	if err != nil {
+		return nil, "", err
+	}
+	refresh, err := c.sign(c.newClaims(base, TokenTypeRefresh, sessionID, refreshID, nowTime, c.refreshTTL()))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        c.Timeout,
+		RefreshExpiresIn: int(c.refreshTTL() / time.Second),
+	}, refreshID, nil
+}
+
+// parseRefreshToken 解析并校验 refresh token
+func (c *CoreJWT) parseRefreshToken(refreshToken string) (*Claims, error) {
+	claims, err := c.ParseToken(refreshToken)
+	if err != nil || claims == nil || claims.TokenType != TokenTypeRefresh || claims.SessionID == "" || claims.ID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	return claims, nil
+}
+
+// refreshTTL refresh token 有效期
+func (c *CoreJWT) refreshTTL() time.Duration {
+	if c.RefreshTimeout > 0 {
+		return time.Duration(c.RefreshTimeout) * time.Second
+	}
+	return defaultRefreshTimeout * time.Second
+}
+
+// refreshError 刷新/注销失败的响应, refresh token 无效时返回 401, redis 等内部错误返回 500
+func refreshError(ctx *gin.Context, err error) {
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenRevoked) || errors.Is(err, ErrRefreshTokenReused) {
+		ctx.JSON(http.StatusUnauthorized, pkgs.ResultInfo{Code: "-1", Msg: err.Error()})
+		return
+	}
+	internalError(ctx, err)
+}
+
+// internalError 会话存储等内部错误返回 500, 原始错误只记录日志, 不返回给客户端
+func internalError(ctx *gin.Context, err error) {
+	logger.Logger.WithOptions(logger.WithContext(ctx.Request.Context())).Error("JWT",
+		zap.String("path", ctx.Request.URL.Path), zap.Error(err))
+	ctx.AbortWithStatusJSON(http.StatusInternalServerError, pkgs.ResultInfo{Code: "-1", Msg: http.StatusText(http.StatusInternalServerError)})
+}
+
+// newTokenID 随机 token 标识
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
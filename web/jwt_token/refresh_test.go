@@ -0,0 +1,145 @@
+package jwt_token
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	testutil.Main(m)
+}
+
+// newTestJWT 基于 miniredis 的 CoreJWT
+func newTestJWT(t *testing.T) (*miniredis.Miniredis, *CoreJWT) {
+	t.Helper()
+	mr, client := testutil.NewRedis(t)
+	return mr, &CoreJWT{Secret: "secret", Timeout: 60, RefreshTimeout: 3600, Redis: client}
+}
+
+// claimsOf 解析 token, 失败时终止测试
+func claimsOf(t *testing.T, j *CoreJWT, token string) *Claims {
+	t.Helper()
+	claims, err := j.ParseToken(token)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+	return claims
+}
+
+func TestRefreshRotation(t *testing.T) {
+	_, j := newTestJWT(t)
+
+	pair, err := j.IssuePair("alice")
+	if err != nil {
+		t.Fatalf("IssuePair: %v", err)
+	}
+	if list, _ := j.Sessions(context.Background(), "alice"); len(list) != 1 {
+		t.Fatalf("IssuePair 应开启会话, got %d", len(list))
+	}
+	if _, err = j.Refresh(pair.AccessToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("access token 不能用于刷新, got %v", err)
+	}
+
+	rotated, err := j.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// 重复使用已轮换的 refresh token, 整个会话失效
+	if _, err = j.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重复使用应返回 ErrRefreshTokenReused, got %v", err)
+	}
+	if err = j.store().Touch(context.Background(), "alice", claimsOf(t, j, rotated.AccessToken).SessionID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("重复使用后 access token 应失效, got %v", err)
+	}
+	if _, err = j.Refresh(rotated.RefreshToken); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Fatalf("会话注销后应返回 ErrRefreshTokenRevoked, got %v", err)
+	}
+}
+
+func TestRefreshContext(t *testing.T) {
+	_, j := newTestJWT(t)
+
+	pair, err := j.IssuePairContext(context.Background(), Claims{Username: "alice"})
+	if err != nil {
+		t.Fatalf("IssuePairContext: %v", err)
+	}
+
+	// 请求已取消时不应轮换会话, 原 refresh token 仍可使用
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = j.RefreshContext(canceled, pair.RefreshToken); !errors.Is(err, context.Canceled) {
+		t.Fatalf("应返回 ctx 的错误, got %v", err)
+	}
+	if err = j.RevokeRefreshTokenContext(canceled, pair.RefreshToken); !errors.Is(err, context.Canceled) {
+		t.Fatalf("应返回 ctx 的错误, got %v", err)
+	}
+	if _, err = j.RefreshContext(context.Background(), pair.RefreshToken); err != nil {
+		t.Fatalf("RefreshContext: %v", err)
+	}
+}
+
+func TestRefreshHandlers(t *testing.T) {
+	mr, j := newTestJWT(t)
+	pair, _ := j.IssuePair("bob")
+
+	r := gin.New()
+	r.POST("/refresh", j.RefreshHandler())
+	r.POST("/logout", j.RevokeHandler())
+	r.GET("/me", j.TokenVerify(), func(c *gin.Context) { c.String(http.StatusOK, c.GetString(pkgs.UsernameKey)) })
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/me", pair.AccessToken, nil); w.Code != http.StatusOK || w.Body.String() != "bob" {
+		t.Fatalf("access token 校验失败: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/me", pair.RefreshToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh token 不能访问接口: %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/refresh", "", RefreshReq{RefreshToken: pair.RefreshToken})
+	var res struct {
+		Data TokenPair `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &res) != nil || res.Data.RefreshToken == "" {
+		t.Fatalf("刷新失败: %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodPost, "/refresh", "", RefreshReq{RefreshToken: pair.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("重复刷新应返回 401: %d", w.Code)
+	}
+
+	pair, _ = j.IssuePair("bob")
+	if w = do(http.MethodPost, "/logout", "", RefreshReq{RefreshToken: pair.RefreshToken}); w.Code != http.StatusOK {
+		t.Fatalf("退出登录失败: %d %s", w.Code, w.Body.String())
+	}
+	if w = do(http.MethodGet, "/me", pair.AccessToken, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("退出登录后 access token 应失效: %d", w.Code)
+	}
+
+	// redis 不可用时返回 500, 不暴露内部错误
+	pair, _ = j.IssuePair("bob")
+	addr := mr.Addr()
+	mr.Close()
+	if w = do(http.MethodPost, "/refresh", "", RefreshReq{RefreshToken: pair.RefreshToken}); w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), addr) {
+		t.Fatalf("内部错误应返回通用提示: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package jwt_token
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+)
+
+// Login 签发 token 对并开启新会话, 记录请求的 User-Agent 和 IP 作为登录设备
+func (c *CoreJWT) Login(ctx *gin.Context, claims Claims) (*TokenPair, error) {
+	return c.issue(ctx.Request.Context(), claims, Session{Device: ctx.Request.UserAgent(), IP: pkgs.GetRemoteIP(ctx)})
+}
+
+// Sessions 用户的有效会话
+func (c *CoreJWT) Sessions(ctx context.Context, username string) ([]*Session, error) {
+	return c.store().List(ctx, username)
+}
+
+// RevokeSession 注销用户的单个会话, 该会话的 access token 和 refresh token 立即失效
+func (c *CoreJWT) RevokeSession(ctx context.Context, username, sessionID string) error {
+	return c.store().Revoke(ctx, username, sessionID)
+}
+
+// RevokeAllSessions 注销用户的所有会话, 即在所有设备退出登录
+func (c *CoreJWT) RevokeAllSessions(ctx context.Context, username string) error {
+	return c.store().RevokeAll(ctx, username)
+}
+
+// SessionsHandler 当前用户的会话列表接口, 需在 TokenVerify 之后; Current 标记当前请求的会话
+func (c *CoreJWT) SessionsHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, pkgs.ResultInfo{Code: "-1", Msg: "未登录"})
+			return
+		}
+
+		list, err := c.Sessions(ctx.Request.Context(), claims.Username)
+		if err != nil {
+			internalError(ctx, err)
+			return
+		}
+		for _, s := range list {
+			s.Current = s.ID == claims.SessionID
+		}
+		ctx.JSON(http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok", Total: int64(len(list)), Data: list})
+	}
+}
+
+// RevokeSessionHandler 注销当前用户指定会话的接口, 会话 ID 取自路由参数 param, 需在 TokenVerify 之后
+func (c *CoreJWT) RevokeSessionHandler(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, pkgs.ResultInfo{Code: "-1", Msg: "未登录"})
+			return
+		}
+
+		if err := c.RevokeSession(ctx.Request.Context(), claims.Username, ctx.Param(param)); err != nil {
+			internalError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok"})
+	}
+}
+
+// LogoutAllHandler 在所有设备退出登录的接口, 需在 TokenVerify 之后
+func (c *CoreJWT) LogoutAllHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := GetClaims(ctx)
+		if !ok {
+			ctx.JSON(http.StatusUnauthorized, pkgs.ResultInfo{Code: "-1", Msg: "未登录"})
+			return
+		}
+
+		if err := c.RevokeAllSessions(ctx.Request.Context(), claims.Username); err != nil {
+			internalError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok"})
+	}
+}
+
+// issue 开启新会话并签发 token 对, meta 提供设备等会话信息
+func (c *CoreJWT) issue(ctx context.Context, claims Claims, meta Session) (*TokenPair, error) {
+	sessionID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	pair, jti, err := c.signPair(claims, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := meta
+	session.ID = sessionID
+	session.Username = claims.Username
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(c.refreshTTL())
+	session.IdleTimeout = time.Duration(c.IdleTimeout) * time.Second
+	session.RefreshID = jti
+	if err = c.store().Save(ctx, &session); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// store 会话存储
+func (c *CoreJWT) store() TokenStore {
+	if c.Store != nil {
+		return c.Store
+	}
+	return NewRedisStore(c.redisClient())
+}
@@ -0,0 +1,187 @@
+package jwt_token
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound 会话不存在、已过期或已注销
+var ErrSessionNotFound = errors.New("jwt: session not found")
+
+// Session 登录会话, 一次登录签发及刷新轮换出的 token 属于同一会话
+type Session struct {
+	ID         string    `json:"id"`               // ID 会话ID, 即 token 中的 sid
+	Username   string    `json:"username"`         // Username 用户名
+	Device     string    `json:"device,omitempty"` // Device 登录设备, 默认为 User-Agent
+	IP         string    `json:"ip,omitempty"`     // IP 登录 IP
+	CreatedAt  time.Time `json:"created_at"`       // CreatedAt 登录时间
+	LastSeenAt time.Time `json:"last_seen_at"`     // LastSeenAt 最后访问时间
+	ExpiresAt  time.Time `json:"expires_at"`       // ExpiresAt 会话最晚过期时间, 刷新 token 时延长
+	Current    bool      `json:"current"`          // Current 是否为当前请求的会话, 仅 SessionsHandler 返回时设置
+
+	IdleTimeout time.Duration `json:"-"` // IdleTimeout 滑动过期时间, 大于0 时超过该时间未访问即过期, 每次访问重新计时
+	RefreshID   string        `json:"-"` // RefreshID 当前有效的 refresh token jti, 用于检测重复使用
+}
+
+// ttl 会话剩余有效期, 启用滑动过期时不超过 IdleTimeout
+func (s *Session) ttl(now time.Time) time.Duration {
+	ttl := s.ExpiresAt.Sub(now)
+	if s.IdleTimeout > 0 && s.IdleTimeout < ttl {
+		ttl = s.IdleTimeout
+	}
+	return ttl
+}
+
+// TokenStore 会话存储, 支持同一用户多个会话
+type TokenStore interface {
+	// Save 保存新会话
+	Save(ctx context.Context, s *Session) error
+	// Touch 校验会话有效并更新最后访问时间, 启用滑动过期时重新计时; 会话无效返回 ErrSessionNotFound.
+	// sessionID 为空表示不带 sid 的旧版 token, 由实现决定是否接受
+	Touch(ctx context.Context, username, sessionID string) error
+	// Rotate 刷新 token 时将会话的 refresh token 从 oldRefreshID 替换为 newRefreshID 并延长至 expiresAt;
+	// 会话不存在返回 ErrRefreshTokenRevoked, oldRefreshID 不是当前值时注销会话并返回 ErrRefreshTokenReused
+	Rotate(ctx context.Context, username, sessionID, oldRefreshID, newRefreshID string, expiresAt time.Time) error
+	// List 用户的有效会话, 按登录时间排序
+	List(ctx context.Context, username string) ([]*Session, error)
+	// Revoke 注销单个会话
+	Revoke(ctx context.Context, username, sessionID string) error
+	// RevokeAll 注销用户的所有会话
+	RevokeAll(ctx context.Context, username string) error
+}
+
+// MemoryStore 进程内会话存储, 用于单元测试和单实例部署
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]*memorySession
+}
+
+// memorySession 会话及其过期时间
+type memorySession struct {
+	Session
+	deadline time.Time
+}
+
+// NewMemoryStore 创建进程内会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]map[string]*memorySession)}
+}
+
+// Save 实现 TokenStore
+func (m *MemoryStore) Save(ctx context.Context, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sessions[s.Username] == nil {
+		m.sessions[s.Username] = make(map[string]*memorySession)
+	}
+	now := time.Now()
+	m.sessions[s.Username][s.ID] = &memorySession{Session: *s, deadline: now.Add(s.ttl(now))}
+	return nil
+}
+
+// Touch 实现 TokenStore, 不接受旧版 token
+func (m *MemoryStore) Touch(ctx context.Context, username, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.get(username, sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	now := time.Now()
+	s.LastSeenAt = now
+	s.deadline = now.Add(s.ttl(now))
+	return nil
+}
+
+// Rotate 实现 TokenStore
+func (m *MemoryStore) Rotate(ctx context.Context, username, sessionID, oldRefreshID, newRefreshID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.get(username, sessionID)
+	if !ok {
+		return ErrRefreshTokenRevoked
+	}
+	if s.RefreshID != oldRefreshID {
+		delete(m.sessions[username], sessionID)
+		return ErrRefreshTokenReused
+	}
+	now := time.Now()
+	s.RefreshID, s.ExpiresAt, s.LastSeenAt = newRefreshID, expiresAt, now
+	s.deadline = now.Add(s.ttl(now))
+	return nil
+}
+
+// List 实现 TokenStore
+func (m *MemoryStore) List(ctx context.Context, username string) ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]*Session, 0, len(m.sessions[username]))
+	for id := range m.sessions[username] {
+		if s, ok := m.get(username, id); ok {
+			copied := s.Session
+			list = append(list, &copied)
+		}
+	}
+	sortSessions(list)
+	return list, nil
+}
+
+// Revoke 实现 TokenStore
+func (m *MemoryStore) Revoke(ctx context.Context, username, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions[username], sessionID)
+	return nil
+}
+
+// RevokeAll 实现 TokenStore
+func (m *MemoryStore) RevokeAll(ctx context.Context, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, username)
+	return nil
+}
+
+// get 获取未过期的会话, 过期时删除; 调用方持有锁
+func (m *MemoryStore) get(username, sessionID string) (*memorySession, bool) {
+	s, ok := m.sessions[username][sessionID]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(s.deadline) {
+		delete(m.sessions[username], sessionID)
+		return nil, false
+	}
+	return s, true
+}
+
+// NoopStore 不保存会话, 用于无状态 JWT: token 在过期前始终有效, 无法注销, 也无法检测 refresh token 重复使用
+type NoopStore struct{}
+
+// Save 实现 TokenStore
+func (NoopStore) Save(ctx context.Context, s *Session) error { return nil }
+
+// Touch 实现 TokenStore
+func (NoopStore) Touch(ctx context.Context, username, sessionID string) error { return nil }
+
+// Rotate 实现 TokenStore
+func (NoopStore) Rotate(ctx context.Context, username, sessionID, oldRefreshID, newRefreshID string, expiresAt time.Time) error {
+	return nil
+}
+
+// List 实现 TokenStore
+func (NoopStore) List(ctx context.Context, username string) ([]*Session, error) { return nil, nil }
+
+// Revoke 实现 TokenStore
+func (NoopStore) Revoke(ctx context.Context, username, sessionID string) error { return nil }
+
+// RevokeAll 实现 TokenStore
+func (NoopStore) RevokeAll(ctx context.Context, username string) error { return nil }
+
+// sortSessions 按登录时间排序
+func sortSessions(list []*Session) {
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+}
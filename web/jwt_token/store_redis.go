@@ -0,0 +1,244 @@
+package jwt_token
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis"
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+var (
+	// sessionSaveScript 保存会话 hash 并加入用户会话索引, 索引过期时间取最晚过期的会话
+	// KEYS: session, index; ARGV: sid, ttl(ms), 绝对有效期(ms), expires_at(ms), field, value...
+	sessionSaveScript = goRedis.NewScript(`
+redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], unpack(ARGV, 5))
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+redis.call("ZADD", KEYS[2], ARGV[4], ARGV[1])
+if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[3]) then
+	redis.call("PEXPIRE", KEYS[2], ARGV[3])
+end
+return 1`)
+
+	// sessionTouchScript 更新最后访问时间, 启用滑动过期时重新计时, 不超过会话最晚过期时间
+	// KEYS: session; ARGV: now(ms); 返回 1 有效, 0 不存在
+	sessionTouchScript = goRedis.NewScript(`
+local v = redis.call("HMGET", KEYS[1], "expires_at", "idle")
+if not v[1] then
+	return 0
+end
+redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+local idle = tonumber(v[2])
+if idle and idle > 0 then
+	local ttl = tonumber(v[1]) - tonumber(ARGV[1])
+	if idle < ttl then
+		ttl = idle
+	end
+	if ttl <= 0 then
+		redis.call("DEL", KEYS[1])
+		return 0
+	end
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1`)
+
+	// sessionRotateScript 轮换 refresh token 并延长会话; refresh token 不是当前值时删除会话
+	// KEYS: session, index; ARGV: sid, old jti, new jti, expires_at(ms), now(ms); 返回 1 成功, 0 不存在, -1 重复使用
+	sessionRotateScript = goRedis.NewScript(`
+local v = redis.call("HMGET", KEYS[1], "refresh_id", "idle")
+if not v[1] then
+	return 0
+end
+if v[1] ~= ARGV[2] then
+	redis.call("DEL", KEYS[1])
+	redis.call("ZREM", KEYS[2], ARGV[1])
+	return -1
+end
+local abs = tonumber(ARGV[4]) - tonumber(ARGV[5])
+local ttl = abs
+local idle = tonumber(v[2])
+if idle and idle > 0 and idle < ttl then
+	ttl = idle
+end
+redis.call("HSET", KEYS[1], "refresh_id", ARGV[3], "expires_at", ARGV[4], "last_seen_at", ARGV[5])
+redis.call("PEXPIRE", KEYS[1], ttl)
+redis.call("ZADD", KEYS[2], ARGV[4], ARGV[1])
+if redis.call("PTTL", KEYS[2]) < abs then
+	redis.call("PEXPIRE", KEYS[2], abs)
+end
+return 1`)
+)
+
+// RedisStore 基于 redis 的会话存储, 会话保存为 hash, 用户的会话 ID 保存在按过期时间排序的 zset 中;
+// 同一用户的 key 使用 hash tag 位于同一 slot, 支持集群模式
+type RedisStore struct {
+	client goRedis.UniversalClient
+}
+
+// NewRedisStore 创建 redis 会话存储, client 为空时使用 redis.Redisclient
+func NewRedisStore(client goRedis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Save 实现 TokenStore
+func (r *RedisStore) Save(ctx context.Context, s *Session) error {
+	now := time.Now()
+	args := []interface{}{
+		s.ID, s.ttl(now).Milliseconds(), s.ExpiresAt.Sub(now).Milliseconds(), s.ExpiresAt.UnixMilli(),
+		"username", s.Username,
+		"device", s.Device,
+		"ip", s.IP,
+		"created_at", s.CreatedAt.UnixMilli(),
+		"last_seen_at", s.LastSeenAt.UnixMilli(),
+		"expires_at", s.ExpiresAt.UnixMilli(),
+		"idle", s.IdleTimeout.Milliseconds(),
+		"refresh_id", s.RefreshID,
+	}
+	return sessionSaveScript.Run(ctx, r.redisClient(), []string{sessionKey(s.Username, s.ID), sessionIndexKey(s.Username)}, args...).Err()
+}
+
+// Touch 实现 TokenStore; 不带 sid 的旧版 token 校验 TokenKey(username) 是否存在
+func (r *RedisStore) Touch(ctx context.Context, username, sessionID string) error {
+	if sessionID == "" {
+		n, err := r.redisClient().Exists(ctx, TokenKey(username)).Result()
+		if err == nil && n == 0 {
+			err = ErrSessionNotFound
+		}
+		return err
+	}
+
+	res, err := sessionTouchScript.Run(ctx, r.redisClient(), []string{sessionKey(username, sessionID)}, time.Now().UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	if res == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Rotate 实现 TokenStore
+func (r *RedisStore) Rotate(ctx context.Context, username, sessionID, oldRefreshID, newRefreshID string, expiresAt time.Time) error {
+	keys := []string{sessionKey(username, sessionID), sessionIndexKey(username)}
+	res, err := sessionRotateScript.Run(ctx, r.redisClient(), keys, sessionID, oldRefreshID, newRefreshID, expiresAt.UnixMilli(), time.Now().UnixMilli()).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return ErrRefreshTokenRevoked
+	case -1:
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// List 实现 TokenStore, 同时清理索引中已过期的会话
+func (r *RedisStore) List(ctx context.Context, username string) ([]*Session, error) {
+	client := r.redisClient()
+	index := sessionIndexKey(username)
+	if err := client.ZRemRangeByScore(ctx, index, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10)).Err(); err != nil {
+		return nil, err
+	}
+	ids, err := client.ZRange(ctx, index, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	pipe := client.Pipeline()
+	cmds := make([]*goRedis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKey(username, id))
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	list := make([]*Session, 0, len(ids))
+	var expired []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			// 滑动过期或被注销
+			expired = append(expired, ids[i])
+			continue
+		}
+		list = append(list, &Session{
+			ID:          ids[i],
+			Username:    fields["username"],
+			Device:      fields["device"],
+			IP:          fields["ip"],
+			CreatedAt:   parseMilli(fields["created_at"]),
+			LastSeenAt:  parseMilli(fields["last_seen_at"]),
+			ExpiresAt:   parseMilli(fields["expires_at"]),
+			IdleTimeout: time.Duration(parseInt(fields["idle"])) * time.Millisecond,
+			RefreshID:   fields["refresh_id"],
+		})
+	}
+	if len(expired) > 0 {
+		_ = client.ZRem(ctx, index, expired...).Err()
+	}
+	sortSessions(list)
+	return list, nil
+}
+
+// Revoke 实现 TokenStore
+func (r *RedisStore) Revoke(ctx context.Context, username, sessionID string) error {
+	_, err := r.redisClient().TxPipelined(ctx, func(pipe goRedis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(username, sessionID))
+		pipe.ZRem(ctx, sessionIndexKey(username), sessionID)
+		return nil
+	})
+	return err
+}
+
+// RevokeAll 实现 TokenStore, 同时删除旧版 token
+func (r *RedisStore) RevokeAll(ctx context.Context, username string) error {
+	client := r.redisClient()
+	index := sessionIndexKey(username)
+	ids, err := client.ZRange(ctx, index, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, sessionKey(username, id))
+	}
+	keys = append(keys, index)
+	if err = client.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	return client.Del(ctx, TokenKey(username)).Err()
+}
+
+// redisClient redis客户端
+func (r *RedisStore) redisClient() goRedis.UniversalClient {
+	if r.client != nil {
+		return r.client
+	}
+	return redis.Redisclient
+}
+
+// sessionKey 会话 hash 的 key
+func sessionKey(username, sessionID string) string {
+	return redis.Key(redis.KeyToken, "session", "{"+username+"}", sessionID)
+}
+
+// sessionIndexKey 用户会话索引 zset 的 key
+func sessionIndexKey(username string) string {
+	return redis.Key(redis.KeyToken, "sessions", "{"+username+"}")
+}
+
+// parseMilli 毫秒时间戳
+func parseMilli(s string) time.Time {
+	return time.UnixMilli(parseInt(s))
+}
+
+// parseInt 解析整数, 失败时为0
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
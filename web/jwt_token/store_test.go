@@ -0,0 +1,181 @@
+package jwt_token
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedisStoreSessions(t *testing.T) {
+	mr, j := newTestJWT(t)
+	ctx := context.Background()
+
+	first, _ := j.IssuePair("alice")
+	second, _ := j.IssuePair("alice")
+	list, err := j.Sessions(ctx, "alice")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("应有 2 个会话, got %d %v", len(list), err)
+	}
+
+	// 注销单个会话不影响其他设备
+	firstID := claimsOf(t, j, first.AccessToken).SessionID
+	if err = j.RevokeSession(ctx, "alice", firstID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err = j.store().Touch(ctx, "alice", firstID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("已注销的会话应失效, got %v", err)
+	}
+	secondID := claimsOf(t, j, second.AccessToken).SessionID
+	if err = j.store().Touch(ctx, "alice", secondID); err != nil {
+		t.Fatalf("其他会话应有效: %v", err)
+	}
+
+	// 旧版 token 依赖 TokenKey
+	if err = j.store().Touch(ctx, "alice", ""); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("未保存的旧版 token 应失效, got %v", err)
+	}
+	_ = mr.Set(TokenKey("alice"), "legacy")
+	if err = j.store().Touch(ctx, "alice", ""); err != nil {
+		t.Fatalf("旧版 token 应有效: %v", err)
+	}
+
+	if err = j.RevokeAllSessions(ctx, "alice"); err != nil {
+		t.Fatalf("RevokeAllSessions: %v", err)
+	}
+	if list, _ = j.Sessions(ctx, "alice"); len(list) != 0 || mr.Exists(TokenKey("alice")) {
+		t.Fatalf("RevokeAllSessions 后应没有会话, got %d", len(list))
+	}
+}
+
+func TestRedisStoreIdleTimeout(t *testing.T) {
+	mr, j := newTestJWT(t)
+	j.IdleTimeout = 60
+	ctx := context.Background()
+
+	pair, _ := j.IssuePair("alice")
+	sid := claimsOf(t, j, pair.AccessToken).SessionID
+
+	// 每次访问重新计时
+	mr.FastForward(40 * time.Second)
+	if err := j.store().Touch(ctx, "alice", sid); err != nil {
+		t.Fatalf("空闲未超时应有效: %v", err)
+	}
+	mr.FastForward(40 * time.Second)
+	if err := j.store().Touch(ctx, "alice", sid); err != nil {
+		t.Fatalf("访问后应重新计时: %v", err)
+	}
+
+	mr.FastForward(61 * time.Second)
+	if err := j.store().Touch(ctx, "alice", sid); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("空闲超时应失效, got %v", err)
+	}
+	if list, _ := j.Sessions(ctx, "alice"); len(list) != 0 {
+		t.Fatalf("List 应清理过期会话, got %d", len(list))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	j := &CoreJWT{Secret: "secret", Timeout: 60, RefreshTimeout: 3600, Store: NewMemoryStore()}
+
+	pair, err := j.IssuePair("alice")
+	if err != nil {
+		t.Fatalf("IssuePair: %v", err)
+	}
+	sid := claimsOf(t, j, pair.AccessToken).SessionID
+	if err = j.store().Touch(ctx, "alice", sid); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if err = j.store().Touch(ctx, "alice", ""); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("MemoryStore 不接受旧版 token, got %v", err)
+	}
+
+	if _, err = j.Refresh(pair.RefreshToken); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err = j.Refresh(pair.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重复使用应返回 ErrRefreshTokenReused, got %v", err)
+	}
+	if list, _ := j.Sessions(ctx, "alice"); len(list) != 0 {
+		t.Fatalf("重复使用后应注销会话, got %d", len(list))
+	}
+}
+
+func TestNoopStore(t *testing.T) {
+	j := &CoreJWT{Secret: "secret", Timeout: 60, RefreshTimeout: 3600, Store: NoopStore{}}
+	pair, _ := j.IssuePair("alice")
+	if _, err := j.Refresh(pair.RefreshToken); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if err := j.RevokeSession(context.Background(), "alice", "any"); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := j.store().Touch(context.Background(), "alice", claimsOf(t, j, pair.AccessToken).SessionID); err != nil {
+		t.Fatalf("NoopStore 的 token 在过期前始终有效: %v", err)
+	}
+}
+
+func TestSessionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, j := newTestJWT(t)
+
+	r := gin.New()
+	r.POST("/login", func(c *gin.Context) {
+		pair, err := j.Login(c, Claims{Username: "bob"})
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, pair.AccessToken)
+	})
+	auth := r.Group("/", j.TokenVerify())
+	auth.GET("/sessions", j.SessionsHandler())
+	auth.DELETE("/sessions/:id", j.RevokeSessionHandler("id"))
+	auth.POST("/logout/all", j.LogoutAllHandler())
+	auth.GET("/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	phone := do(http.MethodPost, "/login", "").Body.String()
+	laptop := do(http.MethodPost, "/login", "").Body.String()
+
+	list, _ := j.Sessions(context.Background(), "bob")
+	if len(list) != 2 || list[0].Device != "test-agent" || list[0].IP == "" {
+		t.Fatalf("Login 应记录设备和 IP: %+v", list)
+	}
+	if w := do(http.MethodGet, "/sessions", laptop); w.Code != http.StatusOK {
+		t.Fatalf("SessionsHandler: %d %s", w.Code, w.Body.String())
+	}
+
+	phoneID := claimsOf(t, j, phone).SessionID
+	if w := do(http.MethodDelete, "/sessions/"+phoneID, laptop); w.Code != http.StatusOK {
+		t.Fatalf("RevokeSessionHandler: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/me", phone); w.Code != http.StatusUnauthorized {
+		t.Fatalf("被注销设备的 token 应失效: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/me", laptop); w.Code != http.StatusOK {
+		t.Fatalf("当前设备应有效: %d", w.Code)
+	}
+
+	if w := do(http.MethodPost, "/logout/all", laptop); w.Code != http.StatusOK {
+		t.Fatalf("LogoutAllHandler: %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/me", laptop); w.Code != http.StatusUnauthorized {
+		t.Fatalf("退出所有设备后 token 应失效: %d", w.Code)
+	}
+}
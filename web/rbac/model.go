@@ -0,0 +1,96 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/bigbigliu/go-core/database/mysql"
+	"gorm.io/gorm"
+)
+
+// Role 角色, 删除后其权限和用户绑定不再生效
+type Role struct {
+	mysql.BasicModel
+	Name        string `gorm:"column:name;size:64;not null;index" json:"name"`
+	Description string `gorm:"column:description;size:255" json:"description"`
+}
+
+// TableName 表名
+func (Role) TableName() string { return "rbac_roles" }
+
+// RolePermission 角色权限
+type RolePermission struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	Role       string `gorm:"column:role;size:64;not null;uniqueIndex:uk_rbac_role_permission" json:"role"`
+	Permission string `gorm:"column:permission;size:128;not null;uniqueIndex:uk_rbac_role_permission" json:"permission"`
+}
+
+// TableName 表名
+func (RolePermission) TableName() string { return "rbac_role_permissions" }
+
+// UserRole 用户角色, 与 token 中的角色合并
+type UserRole struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	Username string `gorm:"column:username;size:64;not null;uniqueIndex:uk_rbac_user_role" json:"username"`
+	Role     string `gorm:"column:role;size:64;not null;uniqueIndex:uk_rbac_user_role" json:"role"`
+}
+
+// TableName 表名
+func (UserRole) TableName() string { return "rbac_user_roles" }
+
+// RoutePermission 路由策略, 按 Sort 升序匹配第一条
+type RoutePermission struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	Method     string `gorm:"column:method;size:16" json:"method"`
+	Path       string `gorm:"column:path;size:255;not null" json:"path"`
+	Permission string `gorm:"column:permission;size:128" json:"permission"`
+	Sort       int    `gorm:"column:sort;not null;default:0" json:"sort"`
+}
+
+// TableName 表名
+func (RoutePermission) TableName() string { return "rbac_route_permissions" }
+
+// Models rbac 表模型, 用于 AutoMigrate
+func Models() []interface{} {
+	return []interface{}{&Role{}, &RolePermission{}, &UserRole{}, &RoutePermission{}}
+}
+
+// GormLoader 从数据库加载策略, 修改表数据后需调用 Enforcer.Invalidate
+type GormLoader struct {
+	db *gorm.DB
+}
+
+// NewGormLoader 创建数据库策略来源
+func NewGormLoader(db *gorm.DB) *GormLoader {
+	return &GormLoader{db: db}
+}
+
+// Load 实现 Loader
+func (l *GormLoader) Load(ctx context.Context) (*Policy, error) {
+	db := l.db.WithContext(ctx)
+	roles := func() *gorm.DB { return db.Model(&Role{}).Select("name") }
+
+	var perms []RolePermission
+	if err := db.Where("role IN (?)", roles()).Find(&perms).Error; err != nil {
+		return nil, err
+	}
+	var users []UserRole
+	if err := db.Where("role IN (?)", roles()).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var routes []RoutePermission
+	if err := db.Order("sort, id").Find(&routes).Error; err != nil {
+		return nil, err
+	}
+
+	p := &Policy{Roles: map[string][]string{}, Users: map[string][]string{}}
+	for _, rp := range perms {
+		p.Roles[rp.Role] = append(p.Roles[rp.Role], rp.Permission)
+	}
+	for _, ur := range users {
+		p.Users[ur.Username] = append(p.Users[ur.Username], ur.Role)
+	}
+	for _, r := range routes {
+		p.Routes = append(p.Routes, Route{Method: r.Method, Path: r.Path, Permission: r.Permission})
+	}
+	return p, nil
+}
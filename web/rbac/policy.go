@@ -0,0 +1,167 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// Wildcard 匹配所有权限, 或作为权限最后一段匹配该前缀下的所有权限, 例如 order:* 匹配 order:write
+	Wildcard = "*"
+
+	permissionSeparator = ":"
+)
+
+// Policy 权限策略, 可来自配置文件或数据库
+type Policy struct {
+	Roles  map[string][]string `json:"roles" yaml:"roles"`   // Roles 角色 -> 权限
+	Users  map[string][]string `json:"users" yaml:"users"`   // Users 用户名 -> 角色, 与 token 中的角色合并
+	Routes []Route             `json:"routes" yaml:"routes"` // Routes 路由策略, 按顺序匹配第一条
+}
+
+// Route 路由策略, 访问匹配的路由需要拥有 Permission
+type Route struct {
+	Method     string `json:"method" yaml:"method"`         // Method 请求方法, 为空或 * 匹配所有方法
+	Path       string `json:"path" yaml:"path"`             // Path 路径模式, * 和 :name 匹配一段, 末尾的 ** 匹配剩余所有段
+	Permission string `json:"permission" yaml:"permission"` // Permission 所需权限, 为空表示放行
+}
+
+// Loader 策略来源
+type Loader interface {
+	// Load 加载完整策略
+	Load(ctx context.Context) (*Policy, error)
+}
+
+// LoaderFunc 函数形式的 Loader
+type LoaderFunc func(ctx context.Context) (*Policy, error)
+
+// Load 实现 Loader
+func (f LoaderFunc) Load(ctx context.Context) (*Policy, error) {
+	return f(ctx)
+}
+
+// RouteConf 配置文件中的路由策略, 例如 config.RbacRouteConf
+type RouteConf interface {
+	// Rule 请求方法、路径模式和所需权限
+	Rule() (method, path, permission string)
+}
+
+// PolicyFromConf 由配置文件中的角色、用户和路由策略生成 Policy, 例如
+// rbac.PolicyFromConf(conf.Roles, conf.Users, conf.Routes), conf 为 config.RbacConf
+func PolicyFromConf[R RouteConf](roles, users map[string][]string, routes []R) *Policy {
+	p := &Policy{Roles: roles, Users: users, Routes: make([]Route, 0, len(routes))}
+	for _, r := range routes {
+		method, path, permission := r.Rule()
+		p.Routes = append(p.Routes, Route{Method: method, Path: path, Permission: permission})
+	}
+	return p
+}
+
+// StaticLoader 固定策略, 用于配置文件定义的角色和权限
+func StaticLoader(p *Policy) Loader {
+	return LoaderFunc(func(ctx context.Context) (*Policy, error) {
+		return p, nil
+	})
+}
+
+// compiledPolicy 预处理后的策略
+type compiledPolicy struct {
+	roles  map[string][]string
+	users  map[string][]string
+	routes []compiledRoute
+}
+
+// compiledRoute 预处理后的路由策略
+type compiledRoute struct {
+	method     string
+	segments   []string
+	permission string
+}
+
+// compile 预处理策略
+func compile(p *Policy) *compiledPolicy {
+	c := &compiledPolicy{roles: map[string][]string{}, users: map[string][]string{}}
+	if p == nil {
+		return c
+	}
+	for role, perms := range p.Roles {
+		c.roles[role] = perms
+	}
+	for user, roles := range p.Users {
+		c.users[user] = roles
+	}
+	for _, r := range p.Routes {
+		method := strings.ToUpper(r.Method)
+		if method == Wildcard {
+			method = ""
+		}
+		c.routes = append(c.routes, compiledRoute{method: method, segments: splitPath(r.Path), permission: r.Permission})
+	}
+	return c
+}
+
+// subjectRoles 用户的全部角色, token 中的角色和策略中为用户配置的角色
+func (c *compiledPolicy) subjectRoles(username string, roles []string) []string {
+	extra := c.users[username]
+	if len(extra) == 0 {
+		return roles
+	}
+	return append(append(make([]string, 0, len(roles)+len(extra)), roles...), extra...)
+}
+
+// allowed 角色中是否有任一角色拥有权限
+func (c *compiledPolicy) allowed(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, granted := range c.roles[role] {
+			if matchPermission(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// route 第一条匹配请求的路由策略
+func (c *compiledPolicy) route(method, path string) (compiledRoute, bool) {
+	segments := splitPath(path)
+	for _, r := range c.routes {
+		if (r.method == "" || r.method == method) && matchSegments(r.segments, segments) {
+			return r, true
+		}
+	}
+	return compiledRoute{}, false
+}
+
+// matchPermission granted 是否包含 required
+func matchPermission(granted, required string) bool {
+	if granted == Wildcard || granted == required {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, permissionSeparator+Wildcard)
+	return ok && strings.HasPrefix(required, prefix+permissionSeparator)
+}
+
+// matchSegments 路径段匹配
+func matchSegments(pattern, path []string) bool {
+	for i, seg := range pattern {
+		if seg == "**" {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		if seg != Wildcard && !strings.HasPrefix(seg, ":") && seg != path[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// splitPath 按 / 拆分路径, 忽略首尾的 /
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
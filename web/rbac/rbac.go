@@ -0,0 +1,224 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigbigliu/go-core/database/redis/cache"
+	"github.com/bigbigliu/go-core/database/redis/eventbus"
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/bigbigliu/go-core/web/jwt_token"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	// TopicInvalidate 策略变更事件, 各实例收到后清除进程内缓存
+	TopicInvalidate = "rbac.invalidate"
+
+	policyCacheKey  = "rbac:policy"
+	defaultCacheTTL = 10 * time.Minute
+	defaultLocalTTL = 30 * time.Second
+)
+
+// ErrMissingLoader 未配置策略来源
+var ErrMissingLoader = errors.New("rbac: loader is required")
+
+// Options 权限配置
+type Options struct {
+	Loader   Loader                  // Loader 策略来源, 配置文件使用 StaticLoader, 数据库使用 NewGormLoader
+	Client   goRedis.UniversalClient // Client 策略缓存的 redis 客户端, 为空时使用 redis.Redisclient; 都未初始化时只使用进程内缓存
+	CacheTTL time.Duration           // CacheTTL redis 缓存时间, 默认10分钟
+	LocalTTL time.Duration           // LocalTTL 进程内缓存时间, 默认30秒
+	Bus      eventbus.Bus            // Bus 广播策略变更, 使其他实例立即生效; 为空时其他实例在 LocalTTL 后生效
+	Realm    string                  // Realm 401/403 响应 WWW-Authenticate 的 realm
+}
+
+// Enforcer 权限校验, 策略按 进程内 -> redis -> Loader 三级缓存
+type Enforcer struct {
+	opts  Options
+	cache *cache.Cache
+	local atomic.Pointer[localPolicy]
+}
+
+// localPolicy 进程内缓存的策略
+type localPolicy struct {
+	policy   *compiledPolicy
+	expireAt time.Time
+}
+
+// New 创建权限校验; 配置 Bus 时订阅 TopicInvalidate
+func New(opts *Options) (*Enforcer, error) {
+	if opts == nil || opts.Loader == nil {
+		return nil, ErrMissingLoader
+	}
+	e := &Enforcer{opts: *opts}
+	if e.opts.CacheTTL <= 0 {
+		e.opts.CacheTTL = defaultCacheTTL
+	}
+	if e.opts.LocalTTL <= 0 {
+		e.opts.LocalTTL = defaultLocalTTL
+	}
+	e.cache = cache.New(&cache.Options{Client: e.opts.Client, NotFoundTTL: -1})
+
+	if e.opts.Bus != nil {
+		err := e.opts.Bus.Subscribe(TopicInvalidate, func(ctx context.Context, ev *eventbus.Event) error {
+			e.local.Store(nil)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Can 角色中是否有任一角色拥有权限
+func (e *Enforcer) Can(ctx context.Context, roles []string, permission string) (bool, error) {
+	p, err := e.policy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.allowed(roles, permission), nil
+}
+
+// UserCan 用户是否拥有权限, roles 为 token 中的角色, 与策略中为用户配置的角色合并
+func (e *Enforcer) UserCan(ctx context.Context, username string, roles []string, permission string) (bool, error) {
+	p, err := e.policy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.allowed(p.subjectRoles(username, roles), permission), nil
+}
+
+// Invalidate 策略变更后调用, 清除 redis 和进程内缓存并通过 Bus 通知其他实例
+func (e *Enforcer) Invalidate(ctx context.Context) error {
+	e.local.Store(nil)
+	if err := e.cache.Delete(ctx, policyCacheKey); err != nil {
+		return err
+	}
+	if e.opts.Bus != nil {
+		return e.opts.Bus.Publish(ctx, TopicInvalidate, struct{}{})
+	}
+	return nil
+}
+
+// RequirePermission 需要拥有全部权限的中间件, 需在 TokenVerify 之后; 权限不足返回 403
+func (e *Enforcer) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return e.require(true, permissions)
+}
+
+// RequireAnyPermission 需要拥有任一权限的中间件, 需在 TokenVerify 之后; 权限不足返回 403
+func (e *Enforcer) RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	return e.require(false, permissions)
+}
+
+// RoutePolicy 按 Policy.Routes 校验的中间件, 需在 TokenVerify 之后; 没有匹配的路由策略时放行
+func (e *Enforcer) RoutePolicy() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := e.requestPolicy(c)
+		if !ok {
+			return
+		}
+		route, matched := p.route(c.Request.Method, c.Request.URL.Path)
+		if !matched || route.permission == "" {
+			c.Next()
+			return
+		}
+		e.check(c, p, true, []string{route.permission})
+	}
+}
+
+// require 校验权限, all 为 true 时需要全部权限
+func (e *Enforcer) require(all bool, permissions []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := e.requestPolicy(c)
+		if !ok {
+			return
+		}
+		e.check(c, p, all, permissions)
+	}
+}
+
+// check 校验当前用户的权限, 通过时继续处理请求
+func (e *Enforcer) check(c *gin.Context, p *compiledPolicy, all bool, permissions []string) {
+	username, roles, ok := subject(c)
+	if !ok {
+		jwt_token.AbortWithAuthError(c, e.opts.Realm, &jwt_token.AuthError{Status: http.StatusUnauthorized}, "未登录")
+		return
+	}
+
+	roles = p.subjectRoles(username, roles)
+	for _, perm := range permissions {
+		allowed := p.allowed(roles, perm)
+		if allowed && !all {
+			c.Next()
+			return
+		}
+		if !allowed && all {
+			e.forbidden(c, username, perm)
+			return
+		}
+	}
+	if !all && len(permissions) > 0 {
+		e.forbidden(c, username, permissions...)
+		return
+	}
+	c.Next()
+}
+
+// forbidden 返回 403 insufficient_scope
+func (e *Enforcer) forbidden(c *gin.Context, username string, permissions ...string) {
+	scope := strings.Join(permissions, " ")
+	logger.Logger.WithOptions(logger.WithContext(c.Request.Context())).Info("RBAC",
+		zap.String("username", username), zap.String("path", c.Request.URL.Path), zap.String("permission", scope), zap.String("msg", "权限不足"))
+	jwt_token.AbortWithAuthError(c, e.opts.Realm, &jwt_token.AuthError{
+		Status:      http.StatusForbidden,
+		Code:        jwt_token.AuthErrInsufficientScope,
+		Description: "permission required",
+		Scope:       scope,
+	}, "权限不足")
+}
+
+// requestPolicy 读取策略, 失败时返回 500
+func (e *Enforcer) requestPolicy(c *gin.Context) (*compiledPolicy, bool) {
+	p, err := e.policy(c.Request.Context())
+	if err != nil {
+		logger.Logger.WithOptions(logger.WithContext(c.Request.Context())).Error("RBAC", zap.String("msg", "加载权限策略失败"), zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, pkgs.ResultInfo{Code: "-1", Msg: "加载权限策略失败"})
+		return nil, false
+	}
+	return p, true
+}
+
+// policy 依次读取进程内缓存、redis 和 Loader
+func (e *Enforcer) policy(ctx context.Context) (*compiledPolicy, error) {
+	if cur := e.local.Load(); cur != nil && time.Now().Before(cur.expireAt) {
+		return cur.policy, nil
+	}
+
+	p, err := cache.Load(e.cache, ctx, policyCacheKey, e.opts.CacheTTL, func(ctx context.Context) (*Policy, error) {
+		return e.opts.Loader.Load(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	compiled := compile(p)
+	e.local.Store(&localPolicy{policy: compiled, expireAt: time.Now().Add(e.opts.LocalTTL)})
+	return compiled, nil
+}
+
+// subject 当前请求的用户名和 token 中的角色
+func subject(c *gin.Context) (string, []string, bool) {
+	if claims, ok := jwt_token.GetClaims(c); ok {
+		return claims.Username, claims.Roles, true
+	}
+	username := c.GetString(pkgs.UsernameKey)
+	return username, nil, username != ""
+}
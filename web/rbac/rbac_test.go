@@ -0,0 +1,265 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bigbigliu/go-core/database"
+	"github.com/bigbigliu/go-core/database/redis/eventbus"
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/bigbigliu/go-core/web/jwt_token"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	testutil.Main(m)
+}
+
+// newTestEnforcer 基于 miniredis 的 Enforcer
+func newTestEnforcer(t *testing.T, mr *miniredis.Miniredis, loader Loader, bus eventbus.Bus) *Enforcer {
+	t.Helper()
+	client := goRedis.NewClient(&goRedis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	e, err := New(&Options{Loader: loader, Client: client, Bus: bus, Realm: "api"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return e
+}
+
+var testPolicy = &Policy{
+	Roles: map[string][]string{
+		"admin":    {Wildcard},
+		"operator": {"order:*", "user:read"},
+		"viewer":   {"order:read"},
+	},
+	Users: map[string][]string{"carol": {"operator"}},
+	Routes: []Route{
+		{Method: http.MethodGet, Path: "/api/health"},
+		{Method: http.MethodGet, Path: "/api/orders/**", Permission: "order:read"},
+		{Path: "/api/orders/:id", Permission: "order:write"},
+		{Path: "/api/users/*/roles", Permission: "user:admin"},
+	},
+}
+
+func TestMatch(t *testing.T) {
+	p := compile(testPolicy)
+	for _, tc := range []struct {
+		roles []string
+		perm  string
+		want  bool
+	}{
+		{[]string{"admin"}, "anything", true},
+		{[]string{"operator"}, "order:write", true},
+		{[]string{"operator"}, "order:item:delete", true},
+		{[]string{"operator"}, "orders:write", false},
+		{[]string{"operator"}, "user:write", false},
+		{[]string{"viewer", "operator"}, "user:read", true},
+		{nil, "order:read", false},
+	} {
+		if got := p.allowed(tc.roles, tc.perm); got != tc.want {
+			t.Errorf("allowed(%v, %s) = %v, want %v", tc.roles, tc.perm, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		method, path, want string
+		matched            bool
+	}{
+		{http.MethodGet, "/api/health", "", true},
+		{http.MethodGet, "/api/orders", "order:read", true},
+		{http.MethodGet, "/api/orders/1/items", "order:read", true},
+		{http.MethodDelete, "/api/orders/1", "order:write", true},
+		{http.MethodDelete, "/api/orders/1/items", "", false},
+		{http.MethodPut, "/api/users/bob/roles/", "user:admin", true},
+		{http.MethodPost, "/api/users", "", false},
+	} {
+		r, ok := p.route(tc.method, tc.path)
+		if ok != tc.matched || r.permission != tc.want {
+			t.Errorf("route(%s %s) = %q %v, want %q %v", tc.method, tc.path, r.permission, ok, tc.want, tc.matched)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	e := newTestEnforcer(t, miniredis.RunT(t), StaticLoader(testPolicy), nil)
+
+	r := gin.New()
+	// 模拟 TokenVerify, X-User 为用户名, X-Roles 为 token 中的角色
+	r.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			var roles []string
+			if v := c.GetHeader("X-Roles"); v != "" {
+				roles = strings.Split(v, ",")
+			}
+			c.Set(jwt_token.ClaimsKey, &jwt_token.Claims{Username: user, Roles: roles})
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.POST("/orders", e.RequirePermission("order:write", "user:read"), ok)
+	r.GET("/reports", e.RequireAnyPermission("report:read", "order:read"), ok)
+	api := r.Group("/api", e.RoutePolicy())
+	api.Any("/*path", ok)
+
+	do := func(method, path, user, roles string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Roles", roles)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/orders", "bob", "viewer")
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="insufficient_scope", error_description="permission required", scope="order:write"`) {
+		t.Fatalf("权限不足应返回 403: %d %s", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if !strings.Contains(w.Body.String(), `"code":"-1"`) {
+		t.Fatalf("403 应返回 ResultInfo: %s", w.Body.String())
+	}
+	if w = do(http.MethodPost, "/orders", "bob", "operator"); w.Code != http.StatusOK {
+		t.Fatalf("operator 应有权限: %d", w.Code)
+	}
+	// 策略中为用户配置的角色
+	if w = do(http.MethodPost, "/orders", "carol", ""); w.Code != http.StatusOK {
+		t.Fatalf("carol 应通过用户角色获得权限: %d", w.Code)
+	}
+	if w = do(http.MethodPost, "/orders", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("未登录应返回 401: %d", w.Code)
+	}
+
+	if w = do(http.MethodGet, "/reports", "bob", "viewer"); w.Code != http.StatusOK {
+		t.Fatalf("拥有任一权限即可访问: %d", w.Code)
+	}
+	if w = do(http.MethodGet, "/reports", "bob", "guest"); w.Code != http.StatusForbidden || !strings.Contains(w.Header().Get("WWW-Authenticate"), `scope="report:read order:read"`) {
+		t.Fatalf("没有任一权限应返回 403: %d %s", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+
+	if w = do(http.MethodGet, "/api/orders/1", "bob", "viewer"); w.Code != http.StatusOK {
+		t.Fatalf("路由策略 order:read: %d", w.Code)
+	}
+	if w = do(http.MethodDelete, "/api/orders/1", "bob", "viewer"); w.Code != http.StatusForbidden {
+		t.Fatalf("路由策略 order:write: %d", w.Code)
+	}
+	if w = do(http.MethodGet, "/api/health", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("没有权限要求的路由应放行: %d", w.Code)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	mr := miniredis.RunT(t)
+	bus := eventbus.NewMemory()
+	var loads atomic.Int32
+	var current atomic.Pointer[Policy]
+	current.Store(&Policy{Roles: map[string][]string{"viewer": {"order:read"}}})
+	loader := LoaderFunc(func(ctx context.Context) (*Policy, error) {
+		loads.Add(1)
+		return current.Load(), nil
+	})
+
+	ctx := context.Background()
+	e1 := newTestEnforcer(t, mr, loader, bus)
+	e2 := newTestEnforcer(t, mr, loader, bus)
+
+	for _, e := range []*Enforcer{e1, e2, e1} {
+		if ok, err := e.Can(ctx, []string{"viewer"}, "order:read"); err != nil || !ok {
+			t.Fatalf("Can: %v %v", ok, err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("策略应只加载一次, 之后命中 redis 和进程内缓存, got %d", n)
+	}
+
+	current.Store(&Policy{Roles: map[string][]string{"viewer": {}}})
+	if err := e1.Invalidate(ctx); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	for _, e := range []*Enforcer{e1, e2} {
+		if ok, _ := e.Can(ctx, []string{"viewer"}, "order:read"); ok {
+			t.Fatalf("策略变更后所有实例应立即生效")
+		}
+	}
+}
+
+func TestWithoutRedis(t *testing.T) {
+	// 未配置 Client 且未调用 redis.InitRedis 时只使用进程内缓存
+	e, err := New(&Options{Loader: StaticLoader(testPolicy)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx := context.Background()
+	if ok, err := e.Can(ctx, []string{"operator"}, "order:write"); err != nil || !ok {
+		t.Fatalf("Can: %v %v", ok, err)
+	}
+	if err = e.Invalidate(ctx); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	if ok, err := e.UserCan(ctx, "carol", nil, "user:read"); err != nil || !ok {
+		t.Fatalf("UserCan: %v %v", ok, err)
+	}
+}
+
+func TestGormLoader(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.New(ctx, &database.Config{
+		DSNParam:     database.DSNParam{Driver: database.DriverSQLite, DbName: testutil.SQLiteDSN(t)},
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	if err = db.AutoMigrate(Models()...); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	ctx = pkgs.WithUsername(ctx, "admin")
+	db = db.WithContext(ctx)
+	db.Create(&[]Role{{Name: "operator"}, {Name: "retired"}})
+	db.Create(&[]RolePermission{{Role: "operator", Permission: "order:*"}, {Role: "retired", Permission: "user:*"}})
+	db.Create(&[]UserRole{{Username: "carol", Role: "operator"}, {Username: "carol", Role: "retired"}})
+	db.Create(&[]RoutePermission{{Path: "/api/orders/**", Permission: "order:read", Sort: 2}, {Method: http.MethodGet, Path: "/api/health", Sort: 1}})
+	db.Where("name = ?", "retired").Delete(&Role{})
+
+	p, err := NewGormLoader(db).Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(p.Roles) != 1 || p.Roles["operator"][0] != "order:*" {
+		t.Fatalf("已删除角色的权限不应生效: %+v", p.Roles)
+	}
+	if len(p.Users["carol"]) != 1 || p.Users["carol"][0] != "operator" {
+		t.Fatalf("用户角色: %+v", p.Users)
+	}
+	if len(p.Routes) != 2 || p.Routes[0].Path != "/api/health" {
+		t.Fatalf("路由策略应按 Sort 排序: %+v", p.Routes)
+	}
+}
+
+// routeConf 与 config.RbacRouteConf 结构相同, rbac 测试不导入 config
+type routeConf struct{ Method, Path, Permission string }
+
+func (r routeConf) Rule() (method, path, permission string) {
+	return r.Method, r.Path, r.Permission
+}
+
+func TestPolicyFromConf(t *testing.T) {
+	roles := map[string][]string{"viewer": {"order:read"}}
+	users := map[string][]string{"1": {"viewer"}}
+	p := PolicyFromConf(roles, users, []routeConf{{"GET", "/orders/*", "order:read"}})
+
+	if len(p.Routes) != 1 || p.Routes[0] != (Route{Method: "GET", Path: "/orders/*", Permission: "order:read"}) {
+		t.Fatalf("routes = %+v", p.Routes)
+	}
+	if !compile(p).allowed(p.Users["1"], "order:read") {
+		t.Errorf("viewer 应有 order:read 权限")
+	}
+}
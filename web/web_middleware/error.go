@@ -0,0 +1,32 @@
+package web_middleware
+
+import (
+	"net/http"
+
+	"github.com/bigbigliu/go-core/logger"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorMiddleware 统一错误响应中间件, 处理函数通过 c.Error(err) 返回错误且未写响应时,
+// 按 pkgs.HTTPStatus 转换状态码, 例如乐观锁冲突返回 409. 4xx 返回错误信息,
+// 5xx 只返回通用提示并记录原始错误, 避免 SQL、驱动等内部错误暴露给客户端
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		err := c.Errors.Last()
+		if err == nil || c.Writer.Written() {
+			return
+		}
+
+		status, msg := pkgs.HTTPStatus(err.Err), err.Error()
+		if status >= http.StatusInternalServerError {
+			logger.Logger.WithOptions(logger.WithContext(c.Request.Context())).Error("Error",
+				zap.String("path", c.Request.URL.Path), zap.Int("status", status), zap.Error(err.Err))
+			msg = http.StatusText(status)
+		}
+		c.JSON(status, pkgs.ResultInfo{Code: "-1", Msg: msg})
+	}
+}
@@ -0,0 +1,44 @@
+package web_middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bigbigliu/go-core/internal/testutil"
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	testutil.Main(m)
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	r := gin.New()
+	r.Use(ErrorMiddleware())
+	r.GET("/conflict", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("order 1: %w", pkgs.ErrConflict))
+	})
+	r.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errors.New("Error 1146: Table 'core.orders' doesn't exist"))
+	})
+
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := do("/conflict"); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "order 1") {
+		t.Fatalf("4xx 应返回错误信息: %d %s", w.Code, w.Body.String())
+	}
+	w := do("/internal")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "1146") || !strings.Contains(w.Body.String(), http.StatusText(http.StatusInternalServerError)) {
+		t.Fatalf("5xx 不应暴露内部错误: %d %s", w.Code, w.Body.String())
+	}
+}
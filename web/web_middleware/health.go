@@ -0,0 +1,25 @@
+package web_middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 健康检查接口, 全部依赖可用时返回 200, 否则返回 503; data 为各依赖的检查结果和连接池统计
+func HealthHandler(timeout time.Duration, checkers ...pkgs.HealthChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list := pkgs.CheckHealth(c.Request.Context(), timeout, checkers...)
+
+		status, res := http.StatusOK, pkgs.ResultInfo{Code: "0", Msg: "ok", Data: list}
+		for _, s := range list {
+			if !s.Healthy {
+				status, res.Code, res.Msg = http.StatusServiceUnavailable, "-1", s.Name+" unavailable"
+				break
+			}
+		}
+		c.JSON(status, res)
+	}
+}
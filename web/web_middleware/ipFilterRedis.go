@@ -6,12 +6,11 @@ import (
 	"sync"
 	"time"
 
+	cacheCtl "github.com/bigbigliu/go-core/database/redis"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 )
 
-const redisKeyPrefix = "ip_requests:"
-
 // IPCounterWithRedis 用于记录IP请求计数和时间
 type IPCounterWithRedis struct {
 	Count      int       // Count ip计数
@@ -19,7 +18,7 @@ type IPCounterWithRedis struct {
 }
 
 // IPFilterWithRedisMiddleware 中间件用于过滤IP并限制请求频率(redis)
-func IPFilterWithRedisMiddleware(redisClient *redis.Client, maxRequestsPerIP int, timeWindow time.Duration) gin.HandlerFunc {
+func IPFilterWithRedisMiddleware(redisClient redis.UniversalClient, maxRequestsPerIP int, timeWindow time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := pkgs.GetRemoteIP(c)
 
@@ -28,8 +27,8 @@ func IPFilterWithRedisMiddleware(redisClient *redis.Client, maxRequestsPerIP int
 		mutex.Lock()
 		defer mutex.Unlock()
 
-		countKey := redisKeyPrefix + ip
-		lastAccessKey := redisKeyPrefix + ip + "_last_access"
+		countKey := cacheCtl.Key(cacheCtl.KeyIPLimit, ip)
+		lastAccessKey := countKey + "_last_access"
 
 		// 从Redis中获取IP请求计数
 		count, _ := redisClient.Get(c, countKey).Int()
@@ -0,0 +1,64 @@
+package web_middleware
+
+import (
+	"net/http"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/bigbigliu/go-core/web/jwt_token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// TenantHeader 默认租户请求头
+	TenantHeader = "X-Tenant-ID"
+)
+
+// TenantExtractor 从请求中提取租户, 返回空字符串表示没有租户
+type TenantExtractor func(c *gin.Context) string
+
+// TenantFromHeader 从请求头提取租户; 请求头由客户端提供未经校验, 只应用于网关已鉴权并覆盖该请求头等可信场景
+func TenantFromHeader(header string) TenantExtractor {
+	return func(c *gin.Context) string {
+		return c.GetHeader(header)
+	}
+}
+
+// TenantFromKey 从 gin.Context 的 key 提取租户, 例如 jwt 校验中间件写入的租户 claim
+func TenantFromKey(key string) TenantExtractor {
+	return func(c *gin.Context) string {
+		return c.GetString(key)
+	}
+}
+
+// TenantMiddleware 租户中间件, 按顺序尝试 extractors, 将租户写入 gin.Context 和 request ctx, 供 database 租户隔离回调使用.
+// 默认使用 token 中经过签名校验的租户 (jwt_token.TenantFromClaims, 需在 TokenVerify 之后); 信任请求头需显式传入 TenantFromHeader.
+// 没有租户时返回 400; 请求同时携带 X-Tenant-ID 且与取得的租户不一致时返回 403, 防止通过请求头切换到其他租户
+func TenantMiddleware(extractors ...TenantExtractor) gin.HandlerFunc {
+	if len(extractors) == 0 {
+		extractors = []TenantExtractor{jwt_token.TenantFromClaims}
+	}
+
+	return func(c *gin.Context) {
+		var tenantID string
+		for _, extract := range extractors {
+			if tenantID = extract(c); tenantID != "" {
+				break
+			}
+		}
+
+		if tenantID == "" {
+			c.JSON(http.StatusBadRequest, pkgs.ResultInfo{Code: "-1", Msg: "租户不能为空"})
+			c.Abort()
+			return
+		}
+		if header := c.GetHeader(TenantHeader); header != "" && header != tenantID {
+			c.JSON(http.StatusForbidden, pkgs.ResultInfo{Code: "-1", Msg: "无权访问该租户"})
+			c.Abort()
+			return
+		}
+
+		c.Set(pkgs.TenantKey, tenantID)
+		c.Request = c.Request.WithContext(pkgs.WithTenant(c.Request.Context(), tenantID))
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package web_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigbigliu/go-core/pkgs"
+	"github.com/bigbigliu/go-core/web/jwt_token"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	r := gin.New()
+	// 模拟 TokenVerify, X-Claim-Tenant 为 token 中的租户
+	r.Use(func(c *gin.Context) {
+		if tenant := c.GetHeader("X-Claim-Tenant"); tenant != "" {
+			c.Set(jwt_token.ClaimsKey, &jwt_token.Claims{Username: "alice", TenantID: tenant})
+		}
+	})
+	handler := func(c *gin.Context) { c.String(http.StatusOK, c.GetString(pkgs.TenantKey)) }
+	r.GET("/claims", TenantMiddleware(), handler)
+	r.GET("/header", TenantMiddleware(TenantFromHeader(TenantHeader)), handler)
+
+	do := func(path, claim, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if claim != "" {
+			req.Header.Set("X-Claim-Tenant", claim)
+		}
+		if header != "" {
+			req.Header.Set(TenantHeader, header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("/claims", "acme", ""); w.Code != http.StatusOK || w.Body.String() != "acme" {
+		t.Fatalf("默认应使用 token 中的租户: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("/claims", "acme", "acme"); w.Code != http.StatusOK {
+		t.Fatalf("请求头与 token 一致应放行: %d", w.Code)
+	}
+	if w := do("/claims", "acme", "globex"); w.Code != http.StatusForbidden {
+		t.Fatalf("请求头与 token 不一致应拒绝: %d", w.Code)
+	}
+	if w := do("/claims", "", "globex"); w.Code != http.StatusBadRequest {
+		t.Fatalf("默认不信任请求头: %d", w.Code)
+	}
+	if w := do("/header", "", "globex"); w.Code != http.StatusOK || w.Body.String() != "globex" {
+		t.Fatalf("显式使用请求头: %d %s", w.Code, w.Body.String())
+	}
+}